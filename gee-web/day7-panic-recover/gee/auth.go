@@ -0,0 +1,298 @@
+package gee
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthUserKey is the Context key of the user authenticated by
+// BasicAuth, APIKey, or JWT from the "sub" claim
+const AuthUserKey = "user"
+
+// JWTClaimsKey is the Context key of the JWTClaims validated by JWT
+const JWTClaimsKey = "jwt_claims"
+
+// Accounts maps user names to passwords for BasicAuth
+type Accounts map[string]string
+
+// unauthorized aborts with 401 and the WWW-Authenticate challenge
+func unauthorized(c *Context, challenge string, message string) {
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.Fail(http.StatusUnauthorized, message)
+}
+
+// secureCompare compares a and b in constant time, whatever their lengths
+func secureCompare(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// BasicAuth checks HTTP Basic credentials against accounts
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm is BasicAuth with the realm sent to the browser,
+// "Authorization Required" by default
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if len(accounts) == 0 {
+		panic("gee: BasicAuth requires at least one account")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	return func(c *Context) {
+		user, password, ok := c.Req.BasicAuth()
+		expected, found := accounts[user]
+		if !ok || !found || !secureCompare(password, expected) {
+			unauthorized(c, challenge, "Unauthorized")
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// APIKeyConfig configures APIKey
+type APIKeyConfig struct {
+	// Header carrying the key, "X-API-Key" by default
+	Header string
+	// Query parameter carrying the key when the header is absent, unused if empty
+	Query string
+	// Keys maps the valid keys to their owner, stored under AuthUserKey
+	Keys map[string]string
+	// Validate, if set, is used instead of Keys
+	Validate func(c *Context, key string) (owner string, ok bool)
+}
+
+// APIKey checks the API key of the request, from the header or the query
+func APIKey(config APIKeyConfig) HandlerFunc {
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	validate := config.Validate
+	if validate == nil {
+		if len(config.Keys) == 0 {
+			panic("gee: APIKey requires Keys or Validate")
+		}
+		validate = func(c *Context, key string) (string, bool) {
+			for k, owner := range config.Keys {
+				if secureCompare(key, k) {
+					return owner, true
+				}
+			}
+			return "", false
+		}
+	}
+	challenge := fmt.Sprintf("APIKey realm=%q, header=%q", "Authorization Required", config.Header)
+	return func(c *Context) {
+		key := c.Req.Header.Get(config.Header)
+		if key == "" && config.Query != "" {
+			key = c.Query(config.Query)
+		}
+		if key == "" {
+			unauthorized(c, challenge, "API key required")
+			return
+		}
+		owner, ok := validate(c, key)
+		if !ok {
+			unauthorized(c, challenge, "invalid API key")
+			return
+		}
+		c.Set(AuthUserKey, owner)
+		c.Next()
+	}
+}
+
+// JWTClaims are the claims of a validated token
+type JWTClaims map[string]interface{}
+
+// JWTHeader is the JOSE header of a token, given to the key lookup
+type JWTHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// JWTConfig configures JWT
+type JWTConfig struct {
+	// Key returns the verification key of a token: a []byte secret for
+	// HS256 or an *rsa.PublicKey for RS256. It is required.
+	Key func(header JWTHeader) (interface{}, error)
+	// TokenLookup is "header:<name>", "query:<name>" or "cookie:<name>",
+	// "header:Authorization" with the Bearer scheme by default
+	TokenLookup string
+	// Issuer and Audience, if set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// Validate, if set, checks the claims after the standard ones
+	Validate func(c *Context, claims JWTClaims) error
+	// Realm of the WWW-Authenticate challenge, "Authorization Required" by default
+	Realm string
+}
+
+// JWT validates HS256 and RS256 bearer tokens and stores their claims
+// under JWTClaimsKey, and the "sub" claim under AuthUserKey
+func JWT(config JWTConfig) HandlerFunc {
+	if config.Key == nil {
+		panic("gee: JWT requires a Key function")
+	}
+	if config.TokenLookup == "" {
+		config.TokenLookup = "header:Authorization"
+	}
+	source, name := config.TokenLookup, ""
+	if i := strings.IndexByte(source, ':'); i >= 0 {
+		source, name = source[:i], source[i+1:]
+	}
+	if config.Realm == "" {
+		config.Realm = "Authorization Required"
+	}
+	challenge := "Bearer realm=" + strconv.Quote(config.Realm)
+
+	return func(c *Context) {
+		var token string
+		switch source {
+		case "query":
+			token = c.Query(name)
+		case "cookie":
+			token, _ = c.Cookie(name)
+		default:
+			token = c.Req.Header.Get(name)
+			if name == "Authorization" {
+				if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+					token = strings.TrimSpace(token[7:])
+				} else {
+					token = ""
+				}
+			}
+		}
+		if token == "" {
+			unauthorized(c, challenge, "token required")
+			return
+		}
+
+		claims, err := parseJWT(token, config, time.Now())
+		if err == nil && config.Validate != nil {
+			err = config.Validate(c, claims)
+		}
+		if err != nil {
+			// the error may come from the Key function, it isn't shown to clients
+			unauthorized(c, challenge+`, error="invalid_token", error_description="invalid token"`, errInvalidToken.Error())
+			return
+		}
+		c.Set(JWTClaimsKey, claims)
+		if sub, ok := claims["sub"].(string); ok {
+			c.Set(AuthUserKey, sub)
+		}
+		c.Next()
+	}
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+// parseJWT verifies the signature and the registered claims of token
+func parseJWT(token string, config JWTConfig, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var header JWTHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	key, err := config.Key(header)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	hashed := sha256.Sum256(signed)
+	// the algorithm must agree with the type of the key, so that an RSA
+	// public key is never used as an HMAC secret
+	switch k := key.(type) {
+	case []byte:
+		if header.Alg != "HS256" {
+			return nil, fmt.Errorf("unexpected signing algorithm %q", header.Alg)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return nil, fmt.Errorf("unexpected signing algorithm %q", header.Alg)
+		}
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hashed[:], signature) != nil {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var claims JWTClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, errInvalidToken
+	}
+
+	// the registered times must be numbers when present
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		if _, ok := claims[name].(float64); !ok && claims[name] != nil {
+			return nil, errInvalidToken
+		}
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(config.Leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if config.Issuer != "" && claims["iss"] != config.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if config.Audience != "" && !hasAudience(claims["aud"], config.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+	return claims, nil
+}
+
+// hasAudience reports whether the aud claim, a string or an array, contains audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
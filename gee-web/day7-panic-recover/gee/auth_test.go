@@ -0,0 +1,157 @@
+package gee
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func authRequest(r *Engine, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.Use(BasicAuthForRealm(Accounts{"geektutu": "secret"}, "admin"))
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, c.GetString(AuthUserKey)) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("geektutu", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("expected 200 geektutu, got %d %q", w.Code, w.Body.String())
+	}
+
+	req.SetBasicAuth("geektutu", "wrong")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Basic realm="admin"` {
+		t.Fatalf("expected 401 with challenge, got %d %v", w.Code, w.Header())
+	}
+	if w := authRequest(r, "/"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("missing credentials should be 401, got %d", w.Code)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	r := New()
+	r.Use(APIKey(APIKeyConfig{Query: "api_key", Keys: map[string]string{"k-123": "billing"}}))
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, c.GetString(AuthUserKey)) })
+
+	if w := authRequest(r, "/", "X-API-Key", "k-123"); w.Code != http.StatusOK || w.Body.String() != "billing" {
+		t.Fatalf("header key should be accepted, got %d %q", w.Code, w.Body.String())
+	}
+	if w := authRequest(r, "/?api_key=k-123"); w.Code != http.StatusOK {
+		t.Fatalf("query key should be accepted, got %d", w.Code)
+	}
+	w := authRequest(r, "/", "X-API-Key", "nope")
+	if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "APIKey ") {
+		t.Fatalf("expected 401 with challenge, got %d %v", w.Code, w.Header())
+	}
+}
+
+func signJWT(t *testing.T, h JWTHeader, key interface{}, claims JWTClaims) string {
+	header, _ := json.Marshal(h)
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		hashed := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("hmac-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Use(JWT(JWTConfig{
+		Key: func(h JWTHeader) (interface{}, error) {
+			switch h.Kid {
+			case "":
+				return secret, nil
+			case "rsa":
+				return &rsaKey.PublicKey, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+		Issuer:   "gee",
+		Audience: "api",
+	}))
+	r.GET("/", func(c *Context) {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		c.String(http.StatusOK, "%s %v", c.GetString(AuthUserKey), claims["role"])
+	})
+
+	hs256 := JWTHeader{Alg: "HS256", Typ: "JWT"}
+	now := time.Now().Unix()
+	valid := JWTClaims{"sub": "geektutu", "role": "admin", "iss": "gee", "aud": []string{"web", "api"}, "exp": now + 60}
+	if w := authRequest(r, "/", "Authorization", "Bearer "+signJWT(t, hs256, secret, valid)); w.Code != http.StatusOK || w.Body.String() != "geektutu admin" {
+		t.Fatalf("HS256 token should be accepted, got %d %q", w.Code, w.Body.String())
+	}
+
+	rsaToken := signJWT(t, JWTHeader{Alg: "RS256", Kid: "rsa"}, rsaKey, valid)
+	if w := authRequest(r, "/", "Authorization", "Bearer "+rsaToken); w.Code != http.StatusOK {
+		t.Fatalf("RS256 token should be accepted, got %d %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "api", "exp": now - 60})},
+		{"not yet valid", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "api", "nbf": now + 60})},
+		{"string exp", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "api", "exp": "soon"})},
+		{"string nbf", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "api", "nbf": "now"})},
+		{"string iat", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "api", "iat": "today"})},
+		{"unknown key", signJWT(t, JWTHeader{Alg: "HS256", Kid: "other"}, secret, valid)},
+		{"wrong issuer", signJWT(t, hs256, secret, JWTClaims{"iss": "other", "aud": "api"})},
+		{"wrong audience", signJWT(t, hs256, secret, JWTClaims{"iss": "gee", "aud": "web"})},
+		{"bad signature", signJWT(t, hs256, []byte("other"), valid)},
+		{"alg none", signJWT(t, JWTHeader{Alg: "none"}, nil, valid)},
+		{"malformed", "abc"},
+	}
+	for _, tt := range tests {
+		w := authRequest(r, "/", "Authorization", "Bearer "+tt.token)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+			t.Fatalf("%s: expected 401 invalid_token, got %d %v", tt.name, w.Code, w.Header())
+		}
+		if strings.Contains(w.Header().Get("WWW-Authenticate"), "unknown key") || strings.Contains(w.Body.String(), "unknown key") {
+			t.Fatalf("%s: the Key error shouldn't reach the client, got %v %s", tt.name, w.Header(), w.Body.String())
+		}
+	}
+
+	w := authRequest(r, "/")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="Authorization Required"` {
+		t.Fatalf("missing token should be 401 with a bare challenge, got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,276 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind decodes the request into obj according to the method and Content-Type,
+// on failure it aborts the chain with 400 and the errors as JSON
+func (c *Context) Bind(obj interface{}) error {
+	err := c.ShouldBind(obj)
+	if err != nil {
+		c.Abort()
+		var verrs ValidationErrors
+		if IsBodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, H{"message": err.Error()})
+		} else if errors.As(err, &verrs) {
+			c.JSON(http.StatusBadRequest, H{"message": "validation failed", "errors": verrs})
+		} else {
+			c.JSON(http.StatusBadRequest, H{"message": err.Error()})
+		}
+	}
+	return err
+}
+
+// ShouldBind is like Bind but leaves the response to the caller
+func (c *Context) ShouldBind(obj interface{}) error {
+	if c.Method == "GET" || c.Method == "HEAD" || c.Method == "DELETE" {
+		return c.ShouldBindQuery(obj)
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.TrimSpace(contentType) {
+	case "application/json":
+		return c.ShouldBindJSON(obj)
+	default:
+		return c.ShouldBindForm(obj)
+	}
+}
+
+// ShouldBindJSON decodes the JSON body into obj and validates it
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("gee: empty request body")
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		if err == io.EOF {
+			return errors.New("gee: empty request body")
+		}
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindQuery decodes the query string into the `form` fields of obj and validates it
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	if err := mapForm(obj, c.Req.URL.Query(), nil, "form"); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindForm decodes the urlencoded or multipart body, and the query string,
+// into the `form` fields of obj and validates it
+func (c *Context) ShouldBindForm(obj interface{}) error {
+	var files map[string][]*multipart.FileHeader
+	if strings.HasPrefix(c.Req.Header.Get("Content-Type"), "multipart/form-data") {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
+		}
+		files = form.File
+	} else if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	if err := mapForm(obj, c.Req.Form, files, "form"); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindURI decodes the path params into the `uri` fields of obj and validates it
+func (c *Context) ShouldBindURI(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+	if err := mapForm(obj, values, nil, "uri"); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+)
+
+// mapForm sets the fields of the struct pointed by obj from values,
+// using the name given by tag, or the field name when the tag is absent.
+// Nested structs are filled with the same values, `tag:"-"` skips a field.
+func mapForm(obj interface{}, values map[string][]string, files map[string][]*multipart.FileHeader, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("gee: binding requires a non-nil pointer to struct, got %T", obj)
+	}
+	return mapStruct(v.Elem(), values, files, tag)
+}
+
+func mapStruct(v reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		unexported := sf.PkgPath != ""
+		if unexported && (!sf.Anonymous || sf.Type.Kind() != reflect.Struct) {
+			continue // like encoding/json, only embedded structs are used
+		}
+		name := sf.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		field := v.Field(i)
+
+		// an unexported embedded struct can't be set, only its fields
+		if (name == "" || unexported) && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			if err := mapStruct(field, values, files, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		switch sf.Type {
+		case fileHeaderType:
+			if fhs := files[name]; len(fhs) > 0 {
+				field.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		case fileHeadersType:
+			if fhs := files[name]; len(fhs) > 0 {
+				field.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+
+		vs, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := setField(field, sf, vs); err != nil {
+			return fmt.Errorf("gee: binding field %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, sf reflect.StructField, vs []string) error {
+	switch field.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), sf, s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vs) != field.Len() {
+			return fmt.Errorf("expected %d values, got %d", field.Len(), len(vs))
+		}
+		for i, s := range vs {
+			if err := setValue(field.Index(i), sf, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return setValue(field, sf, vs[0])
+}
+
+func setValue(v reflect.Value, sf reflect.StructField, s string) error {
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), sf, s); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		if s == "" {
+			return nil
+		}
+		layout := sf.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
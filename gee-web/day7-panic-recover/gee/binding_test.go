@@ -0,0 +1,196 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type signup struct {
+	Name     string   `json:"name" form:"name" binding:"required,min=3,max=10"`
+	Email    string   `json:"email" form:"email" binding:"required,email"`
+	Age      int      `json:"age" form:"age" binding:"min=18,max=130"`
+	Role     string   `json:"role" form:"role" binding:"omitempty,oneof=admin user"`
+	Tags     []string `json:"tags" form:"tag" binding:"max=2"`
+	Nickname *string  `json:"nickname" form:"nickname" binding:"min=2"`
+	Address  struct {
+		City string `json:"city" form:"city" binding:"required"`
+	} `json:"address"`
+}
+
+func TestValidate(t *testing.T) {
+	var s signup
+	s.Name = "ab"
+	s.Email = "not-an-email"
+	s.Age = 12
+	s.Role = "root"
+	s.Tags = []string{"a", "b", "c"}
+	err := Validate(&s)
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	var fields []string
+	for _, e := range verrs {
+		fields = append(fields, e.Field+":"+e.Tag)
+	}
+	want := []string{"name:min", "email:email", "age:min", "role:oneof", "tags:max", "address.city:required"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("got errors %v, want %v", fields, want)
+	}
+
+	s = signup{Name: "geektutu", Email: "gee@example.com", Age: 20}
+	s.Address.City = "Beijing"
+	if err := Validate(&s); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestShouldBindJSON(t *testing.T) {
+	r := New()
+	var got signup
+	r.POST("/signup", func(c *Context) {
+		got = signup{}
+		if err := c.Bind(&got); err != nil {
+			return
+		}
+		c.JSON(http.StatusOK, got)
+	})
+
+	body := `{"name":"geektutu","email":"gee@example.com","age":20,"address":{"city":"Beijing"}}`
+	req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || got.Name != "geektutu" || got.Address.City != "Beijing" {
+		t.Fatalf("binding failed: %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/signup", strings.NewReader(`{"name":"g","age":20}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || len(resp.Errors) != 3 || resp.Errors[0].Field != "name" {
+		t.Fatalf("expected 400 with field errors, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestShouldBindQueryAndURI(t *testing.T) {
+	type query struct {
+		ID      int           `uri:"id" binding:"required"`
+		Page    uint          `form:"page"`
+		Sort    []string      `form:"sort"`
+		Since   time.Time     `form:"since" time_format:"2006-01-02"`
+		Timeout time.Duration `form:"timeout"`
+		Debug   *bool         `form:"debug"`
+	}
+	r := New()
+	var q query
+	r.GET("/items/:id", func(c *Context) {
+		if err := c.ShouldBindURI(&q); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.ShouldBind(&q); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/7?page=2&sort=name&sort=-age&since=2020-01-09&timeout=3s&debug=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	since := time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC)
+	if q.ID != 7 || q.Page != 2 || !reflect.DeepEqual(q.Sort, []string{"name", "-age"}) ||
+		!q.Since.Equal(since) || q.Timeout != 3*time.Second || q.Debug == nil || !*q.Debug {
+		t.Fatalf("unexpected binding %+v", q)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid id, got %d", w.Code)
+	}
+}
+
+type bindingID int
+
+type bindingPage struct {
+	Page int `form:"page" binding:"min=1"`
+}
+
+func TestShouldBindEmbedded(t *testing.T) {
+	type query struct {
+		bindingID
+		bindingPage
+		Name string `form:"name"`
+	}
+	var q query
+	req := httptest.NewRequest("GET", "/?page=3&name=gee&bindingID=9", nil)
+	c := newContext(httptest.NewRecorder(), req)
+	if err := c.ShouldBindQuery(&q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 || q.Name != "gee" || q.bindingID != 0 {
+		t.Fatalf("unexpected binding %+v", q)
+	}
+
+	q = query{}
+	c = newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?page=0", nil))
+	if err := c.ShouldBindQuery(&q); err == nil {
+		t.Fatal("fields of unexported embedded structs should be validated")
+	}
+}
+
+func TestShouldBindForm(t *testing.T) {
+	type upload struct {
+		Title string                `form:"title" binding:"required"`
+		File  *multipart.FileHeader `form:"file" binding:"required"`
+	}
+	r := New()
+	var u upload
+	r.POST("/upload", func(c *Context) {
+		u = upload{}
+		if err := c.Bind(&u); err == nil {
+			c.String(http.StatusOK, u.File.Filename)
+		}
+	})
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "report")
+	fw, _ := mw.CreateFormFile("file", "report.txt")
+	fw.Write([]byte("hello"))
+	mw.Close()
+	req := httptest.NewRequest("POST", "/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "report.txt" || u.Title != "report" {
+		t.Fatalf("multipart binding failed: %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/upload", strings.NewReader(url.Values{"title": {"report"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should be 400, got %d", w.Code)
+	}
+}
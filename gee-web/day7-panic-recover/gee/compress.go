@@ -0,0 +1,314 @@
+package gee
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressEncoder is a streaming encoder, as implemented by
+// gzip.Writer and flate.Writer. Reset lets Compress reuse the encoders.
+type CompressEncoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// Compressor is a content coding offered by Compress, such as brotli
+// with a third party encoder
+type Compressor struct {
+	Encoding string
+	New      func(w io.Writer) (CompressEncoder, error)
+}
+
+// GzipCompressor returns the gzip Compressor at level, see compress/gzip
+func GzipCompressor(level int) Compressor {
+	return Compressor{Encoding: "gzip", New: func(w io.Writer) (CompressEncoder, error) {
+		return gzip.NewWriterLevel(w, level)
+	}}
+}
+
+// DeflateCompressor returns the deflate Compressor at level, see compress/flate
+func DeflateCompressor(level int) Compressor {
+	return Compressor{Encoding: "deflate", New: func(w io.Writer) (CompressEncoder, error) {
+		return flate.NewWriter(w, level)
+	}}
+}
+
+// defaultExcludedContentTypes are already compressed, matched by prefix
+var defaultExcludedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+}
+
+// CompressConfig configures Compress
+type CompressConfig struct {
+	// Compressors in order of preference when the client has none,
+	// gzip then deflate at the default level by default
+	Compressors []Compressor
+	// MinLength is the smallest body compressed, 1024 by default,
+	// a negative value compresses every body. Flushed responses are
+	// always compressed.
+	MinLength int
+	// ExcludedPaths are path prefixes never compressed
+	ExcludedPaths []string
+	// ExcludedContentTypes are Content-Type prefixes never compressed,
+	// common compressed formats by default
+	ExcludedContentTypes []string
+}
+
+// Compress compresses the responses with the coding preferred by the
+// Accept-Encoding of the request. It leaves alone responses that already
+// have a Content-Encoding, partial content, excluded content types and
+// bodies smaller than MinLength.
+func Compress(config CompressConfig) HandlerFunc {
+	if len(config.Compressors) == 0 {
+		config.Compressors = []Compressor{GzipCompressor(gzip.DefaultCompression), DeflateCompressor(flate.DefaultCompression)}
+	}
+	if config.MinLength == 0 {
+		config.MinLength = 1024
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+	pools := make([]*sync.Pool, len(config.Compressors))
+	for i := range pools {
+		pools[i] = &sync.Pool{}
+	}
+
+	return func(c *Context) {
+		for _, prefix := range config.ExcludedPaths {
+			if strings.HasPrefix(c.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		header := c.Writer.Header()
+		if !headerHasToken(header, "Vary", "Accept-Encoding") {
+			header.Add("Vary", "Accept-Encoding")
+		}
+		i := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"), config.Compressors)
+		if i < 0 || c.Method == "HEAD" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			compressor:     config.Compressors[i],
+			pool:           pools[i],
+			config:         &config,
+		}
+		finished := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if !finished {
+				// a handler panicked, Recovery answers 500 if nothing was sent
+				w.discard()
+				return
+			}
+			w.close()
+		}()
+		c.Writer = w
+		c.Next()
+		finished = true
+	}
+}
+
+// negotiateEncoding returns the index of the compressor with the highest
+// q-value in accept, the first one on ties, or -1
+func negotiateEncoding(accept string, compressors []Compressor) int {
+	best, bestQ := -1, 0.0
+	for i, compressor := range compressors {
+		q, wildcard := -1.0, -1.0
+		for _, part := range strings.Split(accept, ",") {
+			coding, params := part, ""
+			if j := strings.IndexByte(part, ';'); j >= 0 {
+				coding, params = part[:j], part[j+1:]
+			}
+			coding = strings.TrimSpace(coding)
+			value := 1.0
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				if f, err := strconv.ParseFloat(params[2:], 64); err == nil {
+					value = f
+				}
+			}
+			if strings.EqualFold(coding, compressor.Encoding) {
+				q = value
+			} else if coding == "*" {
+				wildcard = value
+			}
+		}
+		if q < 0 {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+func headerHasToken(header http.Header, key string, token string) bool {
+	for _, value := range header.Values(key) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the beginning of the body until it knows whether
+// to compress, then writes through the encoder or straight to ResponseWriter
+type compressWriter struct {
+	ResponseWriter
+	compressor Compressor
+	pool       *sync.Pool
+	config     *CompressConfig
+	buf        []byte
+	decided    bool
+	encoder    CompressEncoder
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.config.MinLength {
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.decided && len(w.buf) > 0 {
+		return len(w.buf)
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush sends what was written so far, compressed if possible whatever its length
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide sets up the encoder if the response is worth compressing,
+// then writes the buffered body
+func (w *compressWriter) decide(streaming bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.shouldCompress(streaming) {
+		w.encoder = w.newEncoder()
+	}
+	if w.encoder != nil {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.compressor.Encoding)
+		// the compressed bytes differ, a strong validator must not be reused
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *compressWriter) shouldCompress(streaming bool) bool {
+	status := w.Status()
+	if !bodyAllowedForStatus(status) || status == http.StatusPartialContent {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if !streaming && (len(w.buf) == 0 || len(w.buf) < w.config.MinLength) {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	for _, prefix := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// newEncoder returns a pooled encoder writing to ResponseWriter,
+// or nil if the compressor fails so that the body is sent as is
+func (w *compressWriter) newEncoder() CompressEncoder {
+	if encoder, ok := w.pool.Get().(CompressEncoder); ok {
+		encoder.Reset(w.ResponseWriter)
+		return encoder
+	}
+	encoder, err := w.compressor.New(w.ResponseWriter)
+	if err != nil {
+		return nil
+	}
+	return encoder
+}
+
+// discard drops the buffered body, the encoder is only put back in the pool
+func (w *compressWriter) discard() {
+	w.buf = nil
+	if w.encoder != nil {
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+// close ends the response once the handlers returned
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
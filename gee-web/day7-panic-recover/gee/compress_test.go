@@ -0,0 +1,130 @@
+package gee
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compressRequest(r *Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, body io.Reader) string {
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("geektutu ", 200)
+	r := New()
+	r.Use(Compress(CompressConfig{ExcludedPaths: []string{"/raw"}}))
+	r.GET("/large", func(c *Context) { c.JSON(http.StatusOK, H{"text": large}) })
+	r.GET("/small", func(c *Context) { c.String(http.StatusOK, "small") })
+	r.GET("/raw", func(c *Context) { c.String(http.StatusOK, large) })
+	r.GET("/png", func(c *Context) { c.Data(http.StatusOK, append([]byte("\x89PNG\r\n\x1a\n"), large...)) })
+
+	w := compressRequest(r, "/large", "deflate;q=0.5, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a gzip JSON response, got %v", w.Header())
+	}
+	if body := gunzip(t, w.Body); !strings.Contains(body, large) {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	w = compressRequest(r, "/large", "gzip;q=0.2, deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate, got %v", w.Header())
+	}
+	data, _ := io.ReadAll(flate.NewReader(w.Body))
+	if !strings.Contains(string(data), large) {
+		t.Fatal("unexpected deflate body")
+	}
+
+	for _, tt := range []struct{ path, accept string }{
+		{"/large", ""}, {"/large", "gzip;q=0, br"}, {"/small", "gzip"}, {"/raw", "gzip"}, {"/png", "gzip"},
+	} {
+		w := compressRequest(r, tt.path, tt.accept)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s with %q should not be compressed, got %d %v", tt.path, tt.accept, w.Code, w.Header())
+		}
+	}
+}
+
+func TestCompressPanic(t *testing.T) {
+	r := New()
+	r.Use(Recovery(), Compress(CompressConfig{}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := compressRequest(r, "/", "gzip")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "partial") || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("Recovery should answer 500 without the partial body, got %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestCompressStaticAndStream(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("body { color: red; }\n", 100)
+	if err := os.WriteFile(filepath.Join(dir, "site.css"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Use(Compress(CompressConfig{}))
+	r.Static("/assets", dir)
+	r.GET("/events", func(c *Context) {
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			c.SSEvent("tick", i)
+			i++
+			return i < 3
+		})
+	})
+
+	w := compressRequest(r, "/assets/site.css", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" ||
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("expected a gzip stylesheet, got %v", w.Header())
+	}
+	if body := gunzip(t, w.Body); body != content {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	req := httptest.NewRequest("GET", "/assets/site.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Encoding") != "" || w.Body.String() != "body" {
+		t.Fatalf("partial content should not be compressed, got %d %v", w.Code, w.Header())
+	}
+
+	w = compressRequest(r, "/events", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || !w.Flushed {
+		t.Fatalf("expected a flushed gzip stream, got %v", w.Header())
+	}
+	if body := gunzip(t, w.Body); strings.Count(body, "event:tick") != 3 {
+		t.Fatalf("unexpected stream %q", body)
+	}
+}
@@ -1,59 +1,340 @@
 package gee
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"io"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gee/render"
 )
 
 type H map[string]interface{}
 
+// abortIndex is set as index to stop the handler chain
+const abortIndex int = math.MaxInt32
+
 type Context struct {
 	// origin objects
-	Writer http.ResponseWriter
-	Req    *http.Request
+	Writer    ResponseWriter
+	Req       *http.Request
+	writermem responseWriter
 	// request info
 	Path   string
 	Method string
-	Params map[string]string
-	// response info
-	StatusCode int
+	Params Params
 	// middleware
 	handlers []HandlerFunc
 	index    int
+	// request-scoped values, guarded by mu
+	Keys map[string]interface{}
+	mu   sync.RWMutex
+	// SameSite attribute of the cookies set by SetCookie
+	sameSite http.SameSite
+	// HTMLRender of the group of the route, the engine's when nil
+	htmlRender render.HTMLRender
 	// engine pointer
 	engine *Engine
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Path:   req.URL.Path,
-		Method: req.Method,
-		Req:    req,
-		Writer: w,
-		index:  -1,
+	c := &Context{}
+	c.reset(w, req)
+	return c
+}
+
+// reset prepares a pooled Context for a new request, keeping the
+// memory of Params
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.handlers = nil
+	c.index = -1
+	c.Keys = nil
+	c.sameSite = 0
+	c.htmlRender = nil
+}
+
+// Copy returns a copy of the Context that may be used after the handler
+// returns, e.g. by a goroutine, since the Context itself is reused for
+// other requests. The copy must not write the response.
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:        c.Req,
+		Path:       c.Path,
+		Method:     c.Method,
+		Params:     append(Params(nil), c.Params...),
+		index:      abortIndex,
+		sameSite:   c.sameSite,
+		htmlRender: c.htmlRender,
+		engine:     c.engine,
 	}
+	cp.writermem = c.writermem
+	cp.writermem.ResponseWriter = nil
+	cp.Writer = &cp.writermem
+	c.mu.RLock()
+	if c.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(c.Keys))
+		for key, value := range c.Keys {
+			cp.Keys[key] = value
+		}
+	}
+	c.mu.RUnlock()
+	return cp
 }
 
+// Next runs the remaining handlers, it stops as soon as the request
+// context is done, e.g. the client went away or Timeout expired
 func (c *Context) Next() {
 	c.index++
 	s := len(c.handlers)
 	for ; c.index < s; c.index++ {
+		if err := c.Req.Context().Err(); err != nil {
+			c.abortWithContextErr(err)
+			return
+		}
 		c.handlers[c.index](c)
 	}
+	if err := c.Req.Context().Err(); err != nil {
+		c.abortWithContextErr(err)
+	}
+}
+
+// abortWithContextErr stops the chain and answers 503 if nothing was written yet
+func (c *Context) abortWithContextErr(err error) {
+	c.Abort()
+	if !c.Writer.Written() {
+		c.JSON(http.StatusServiceUnavailable, H{"message": err.Error()})
+	}
+}
+
+// Abort prevents the pending handlers from being called,
+// the current handler still runs to its end
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether the chain was stopped by Abort or Fail
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
 }
 
+// Fail aborts the chain and answers code with the error message as JSON,
+// the message is only logged if the response was already started
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
+	c.Abort()
+	if c.Writer.Written() {
+		log.Printf("[%d] %s: %s (response already written)", code, c.Req.RequestURI, err)
+		return
+	}
 	c.JSON(code, H{"message": err})
 }
 
+// Context returns the context of the request
+func (c *Context) Context() context.Context {
+	return c.Req.Context()
+}
+
+// Deadline, Done and Err delegate to the request context,
+// so that *Context can be passed where a context.Context is expected
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value returns the value set by Set for string keys,
+// and falls back to the request context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	return c.Req.Context().Value(key)
+}
+
+// Set stores a value for this request only, e.g. the authenticated user
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+	c.mu.Unlock()
+}
+
+// Get returns the value stored for key and whether it exists
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	value, exists = c.Keys[key]
+	c.mu.RUnlock()
+	return
+}
+
+// MustGet returns the value stored for key, it panics if there is none
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("gee: key \"" + key + "\" does not exist")
+}
+
+// GetString returns the value of key as a string, or "" if absent or of another type
+func (c *Context) GetString(key string) (s string) {
+	if value, ok := c.Get(key); ok {
+		s, _ = value.(string)
+	}
+	return
+}
+
+// GetBool returns the value of key as a bool
+func (c *Context) GetBool(key string) (b bool) {
+	if value, ok := c.Get(key); ok {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+// GetInt returns the value of key as an int
+func (c *Context) GetInt(key string) (i int) {
+	if value, ok := c.Get(key); ok {
+		i, _ = value.(int)
+	}
+	return
+}
+
+// GetInt64 returns the value of key as an int64
+func (c *Context) GetInt64(key string) (i int64) {
+	if value, ok := c.Get(key); ok {
+		i, _ = value.(int64)
+	}
+	return
+}
+
+// GetFloat64 returns the value of key as a float64
+func (c *Context) GetFloat64(key string) (f float64) {
+	if value, ok := c.Get(key); ok {
+		f, _ = value.(float64)
+	}
+	return
+}
+
+// GetTime returns the value of key as a time.Time
+func (c *Context) GetTime(key string) (t time.Time) {
+	if value, ok := c.Get(key); ok {
+		t, _ = value.(time.Time)
+	}
+	return
+}
+
+// GetDuration returns the value of key as a time.Duration
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if value, ok := c.Get(key); ok {
+		d, _ = value.(time.Duration)
+	}
+	return
+}
+
+// GetStringSlice returns the value of key as a []string
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if value, ok := c.Get(key); ok {
+		ss, _ = value.([]string)
+	}
+	return
+}
+
+// GetStringMap returns the value of key as a map[string]interface{}
+func (c *Context) GetStringMap(key string) (sm map[string]interface{}) {
+	if value, ok := c.Get(key); ok {
+		sm, _ = value.(map[string]interface{})
+	}
+	return
+}
+
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
+}
+
+// ClientIP returns the IP of the client. The X-Forwarded-For and X-Real-IP
+// headers are only used when Engine.ForwardedByClientIP is set, i.e. behind
+// a proxy which sets them, since clients can forge them.
+func (c *Context) ClientIP() string {
+	if c.engine != nil && c.engine.ForwardedByClientIP {
+		if forwarded := c.Req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if i := strings.IndexByte(forwarded, ','); i >= 0 {
+				forwarded = forwarded[:i]
+			}
+			if ip := strings.TrimSpace(forwarded); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return host
 }
 
+// Cookie returns the unescaped value of the named request cookie,
+// or http.ErrNoCookie if there is none
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, _ := url.QueryUnescape(cookie.Value)
+	return value, nil
+}
+
+// SetSameSite sets the SameSite attribute of the cookies set afterwards
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie adds a Set-Cookie header, value is escaped and path defaults to "/".
+// maxAge < 0 deletes the cookie, 0 makes it a session cookie.
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// PostForm returns the form value of key, a multipart body is parsed
+// with Engine.MaxMultipartMemory
 func (c *Context) PostForm(key string) string {
+	c.MultipartForm()
 	return c.Req.FormValue(key)
 }
 
@@ -61,8 +342,8 @@ func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
+// Status sets the status code, it is sent along with the first write
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.Writer.WriteHeader(code)
 }
 
@@ -70,32 +351,168 @@ func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
+// Render writes the status code, unless code is negative, and the body of r.
+// A render error answers 500 if the response hasn't started.
+func (c *Context) Render(code int, r render.Render) {
+	if code > 0 {
+		c.Status(code)
+	}
+	if !bodyAllowedForStatus(c.Writer.Status()) {
+		r.WriteContentType(c.Writer)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// bodyAllowedForStatus reports whether a response of this status may have a body
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
 func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+	c.Render(code, render.String{Format: format, Data: values})
 }
 
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
-	}
+	c.Render(code, render.JSON{Data: obj})
+}
+
+// IndentedJSON renders obj as pretty printed JSON, mostly for humans
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+// SecureJSON prefixes JSON arrays with "while(1);" against JSON hijacking
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, render.SecureJSON{Data: obj})
+}
+
+// AsciiJSON renders obj as JSON with non-ASCII characters escaped
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, render.AsciiJSON{Data: obj})
+}
+
+// PureJSON renders obj as JSON without escaping HTML characters
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Render(code, render.PureJSON{Data: obj})
+}
+
+// JSONP wraps the JSON in the function named by the callback query parameter
+func (c *Context) JSONP(code int, obj interface{}) {
+	c.Render(code, render.JSONP{Callback: c.Query("callback"), Data: obj})
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, render.XML{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, render.YAML{Data: obj})
+}
+
+// ProtoBuf renders obj with render.ProtoMarshal
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Render(code, render.ProtoBuf{Data: obj})
 }
 
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Writer.Write(data)
+	c.Render(code, render.Data{Data: data})
+}
+
+// DataFromReader streams reader as the body, contentLength is omitted when negative
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	c.Render(code, render.Reader{
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Reader:        reader,
+		Headers:       extraHeaders,
+	})
 }
 
 // HTML template render
 // refer https://golang.org/pkg/html/template/
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
+	r := c.htmlRender
+	if r == nil {
+		var err error
+		if r, err = c.engine.htmlRender(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if r == nil {
+		c.Fail(http.StatusInternalServerError, "gee: no HTML templates loaded")
+		return
+	}
+	c.Render(code, r.Instance(name, data))
+}
+
+// Redirect answers with a redirection to location, code is a 3xx or 201
+func (c *Context) Redirect(code int, location string) {
+	c.Render(-1, render.Redirect{Code: code, Request: c.Req, Location: location})
+}
+
+// File serves the content of the file at filepath
+func (c *Context) File(filepath string) {
+	http.ServeFile(c.Writer, c.Req, filepath)
+}
+
+// FileAttachment serves the file at filepath as a download named filename
+func (c *Context) FileAttachment(filepath string, filename string) {
+	if isASCII(filename) {
+		c.SetHeader("Content-Disposition", `attachment; filename="`+strings.Replace(filename, `"`, `\"`, -1)+`"`)
+	} else {
+		c.SetHeader("Content-Disposition", `attachment; filename*=UTF-8''`+url.PathEscape(filename))
+	}
+	http.ServeFile(c.Writer, c.Req, filepath)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf || s[i] < ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// Push initiates an HTTP/2 server push of target, it returns
+// http.ErrNotSupported when the connection doesn't support it
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	pusher := c.Writer.Pusher()
+	if pusher == nil {
+		return http.ErrNotSupported
+	}
+	return pusher.Push(target, opts)
+}
+
+// SSEvent writes a Server-Sent Event named name
+func (c *Context) SSEvent(name string, message interface{}) {
+	c.Render(-1, render.SSEvent{Event: name, Data: message})
+}
+
+// Stream calls step and flushes the response until step returns false,
+// it returns true if the client went away before
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	for {
+		select {
+		case <-c.Done():
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
 	}
 }
@@ -0,0 +1,271 @@
+package gee
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextKeys(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.Set("user", "geektutu")
+		c.Set("admin", true)
+		c.Set("age", 20)
+		c.Next()
+	})
+	r.GET("/me", func(c *Context) {
+		var ctx context.Context = c
+		if c.MustGet("user") != "geektutu" || ctx.Value("user") != "geektutu" {
+			t.Error("user should be passed from the middleware")
+		}
+		if c.GetString("user") != "geektutu" || !c.GetBool("admin") || c.GetInt("age") != 20 {
+			t.Error("typed getters should return the stored values")
+		}
+		if c.GetString("age") != "" || c.GetInt("missing") != 0 {
+			t.Error("typed getters should return zero values on mismatch")
+		}
+		if c.Context() != c.Req.Context() {
+			t.Error("Context should return the request context")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/me", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustGet should panic on missing keys")
+		}
+	}()
+	c.MustGet("missing")
+}
+
+func TestTimeout(t *testing.T) {
+	r := New()
+	var reached bool
+	r.GET("/slow", Timeout(10*time.Millisecond), func(c *Context) {
+		select {
+		case <-c.Done():
+		case <-time.After(time.Second):
+			t.Error("Done should be closed by the deadline")
+		}
+	}, func(c *Context) {
+		reached = true
+	})
+	r.GET("/fast", Timeout(time.Second), func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || reached {
+		t.Fatalf("expired request should be 503 and skip pending handlers, got %d, reached %t", w.Code, reached)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestTimeoutBlockingHandler(t *testing.T) {
+	r := New()
+	finished := make(chan struct{})
+	r.GET("/", Timeout(20*time.Millisecond), func(c *Context) {
+		defer close(finished)
+		time.Sleep(200 * time.Millisecond) // ignores c.Done()
+		c.String(http.StatusOK, "late")
+	})
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("Timeout should answer at the deadline, took %s", elapsed)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	<-finished
+	if strings.Contains(w.Body.String(), "late") {
+		t.Fatalf("late write should be dropped, got %q", w.Body.String())
+	}
+}
+
+func TestTimeoutKeepsResponse(t *testing.T) {
+	r := New()
+	var user string
+	r.GET("/", func(c *Context) {
+		c.Next()
+		user = c.GetString("user")
+	}, Timeout(time.Second), func(c *Context) {
+		c.Set("user", "geektutu")
+		c.Writer.Header().Set("X-Handler", "gee")
+		c.String(http.StatusCreated, "done")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "done" || w.Header().Get("X-Handler") != "gee" {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if user != "geektutu" {
+		t.Fatalf("keys set under Timeout should be kept, got %q", user)
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	r := New()
+	var reached bool
+	r.GET("/", func(c *Context) { reached = true })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if reached || w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("canceled request shouldn't run handlers, got %d, reached %t", w.Code, reached)
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	r := New()
+	var status, size int
+	r.Use(func(c *Context) {
+		c.Next()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+	r.GET("/raw", func(c *Context) {
+		c.Writer.WriteHeader(http.StatusCreated)
+		c.Writer.Write([]byte("created"))
+	})
+	r.GET("/partial", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		c.Fail(http.StatusInternalServerError, "template error")
+	})
+	r.GET("/status", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/raw", nil))
+	if w.Code != http.StatusCreated || status != http.StatusCreated || size != 7 {
+		t.Fatalf("expected 201 with 7 bytes, got %d %d %d", w.Code, status, size)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" || status != http.StatusOK {
+		t.Fatalf("Fail after a write shouldn't change the response, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+	if w.Code != http.StatusNoContent || size != -1 {
+		t.Fatalf("expected 204 with no body, got %d %d", w.Code, size)
+	}
+
+	rw := &responseWriter{}
+	rw.reset(httptest.NewRecorder())
+	if _, _, err := rw.Hijack(); err == nil {
+		t.Fatal("Hijack should fail when the underlying writer doesn't support it")
+	}
+	if rw.Pusher() != nil {
+		t.Fatal("Pusher should be nil when the underlying writer doesn't support it")
+	}
+}
+
+type xmlUser struct {
+	Name string `xml:"name"`
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    H{"name": "geektutu"},
+			XMLData: xmlUser{"geektutu"},
+		})
+	})
+	cases := map[string]string{
+		"":                               "application/json",
+		"application/xml":                "application/xml",
+		"text/html;q=0.9, application/*": "application/json",
+		"application/x-yaml, */*;q=0.1":  "application/x-yaml",
+		"application/json;q=0.5, text/*;q=0.1, application/xml;q=0.8": "application/xml",
+	}
+	for accept, want := range cases {
+		req := httptest.NewRequest("GET", "/user", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != want {
+			t.Fatalf("Accept %q should render %s, got %d %s", accept, want, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+
+	r.GET("/name", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON, MIMEPlain}, Data: "geektutu"})
+	})
+	req := httptest.NewRequest("GET", "/name", nil)
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("text/plain should render Data as text, got %d %s %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/user", nil)
+	req.Header.Set("Accept", "image/png")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("unacceptable format should be 406, got %d", w.Code)
+	}
+}
+
+func TestStreamAndAttachment(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		n := 0
+		c.Stream(func(w io.Writer) bool {
+			n++
+			c.SSEvent("tick", H{"n": n})
+			return n < 2
+		})
+	})
+	r.GET("/download", func(c *Context) {
+		c.FileAttachment("context_test.go", "报告.txt")
+	})
+	r.GET("/empty", func(c *Context) {
+		c.JSON(http.StatusNoContent, H{"ignored": true})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if w.Body.String() != "event:tick\ndata:{\"n\":1}\n\nevent:tick\ndata:{\"n\":2}\n\n" || !w.Flushed {
+		t.Fatalf("unexpected event stream %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/download", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Disposition") != "attachment; filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt" {
+		t.Fatalf("unexpected attachment %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/empty", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("204 shouldn't have a body, got %q", w.Body.String())
+	}
+}
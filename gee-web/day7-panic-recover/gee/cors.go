@@ -0,0 +1,148 @@
+package gee
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures CORS
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, "*" allows any origin and
+	// one wildcard may be used per entry, e.g. "https://*.example.com".
+	// "*" can't be used with AllowCredentials.
+	AllowOrigins []string
+	// AllowOriginRegexps are regular expressions matched against the whole origin
+	AllowOriginRegexps []string
+	// AllowOriginFunc, if set, is asked about the origins not allowed above
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods defaults to GET, POST, PUT, PATCH, DELETE, HEAD and OPTIONS
+	AllowMethods []string
+	// AllowHeaders lists the request headers allowed in preflight,
+	// the requested headers are allowed when it is empty
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers readable by the browser
+	ExposeHeaders []string
+	// AllowCredentials lets the browser send cookies and credentials
+	AllowCredentials bool
+	// MaxAge is how long the preflight response may be cached, 0 omits it
+	MaxAge time.Duration
+}
+
+type originPattern struct {
+	prefix, suffix string
+}
+
+// CORS handles cross-origin requests. Preflight requests are answered with
+// 204 and stop the chain, so register it with engine.Use: the engine
+// middlewares also run before NoRoute and NoMethod, which makes preflight
+// work for routes registered without OPTIONS. It panics if any origin is
+// allowed together with credentials, which would let every website make
+// credentialed requests.
+func CORS(config CORSConfig) HandlerFunc {
+	allowAll := false
+	exact := make(map[string]bool)
+	var patterns []originPattern
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			allowAll = true
+		case i >= 0:
+			patterns = append(patterns, originPattern{origin[:i], origin[i+1:]})
+		default:
+			exact[origin] = true
+		}
+	}
+	if allowAll && config.AllowCredentials {
+		panic("gee: CORS can't allow all origins with AllowCredentials, list the origins instead")
+	}
+	regexps := make([]*regexp.Regexp, len(config.AllowOriginRegexps))
+	for i, expr := range config.AllowOriginRegexps {
+		regexps[i] = regexp.MustCompile("^(?:" + expr + ")$")
+	}
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := ""
+	if config.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+
+	allowed := func(origin string) bool {
+		lower := strings.ToLower(origin)
+		if allowAll || exact[lower] {
+			return true
+		}
+		for _, p := range patterns {
+			if len(lower) >= len(p.prefix)+len(p.suffix) && strings.HasPrefix(lower, p.prefix) && strings.HasSuffix(lower, p.suffix) {
+				return true
+			}
+		}
+		for _, re := range regexps {
+			if re.MatchString(origin) {
+				return true
+			}
+		}
+		return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+	}
+
+	return func(c *Context) {
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Method == "OPTIONS" && c.Req.Header.Get("Access-Control-Request-Method") != ""
+
+		if !allowed(origin) {
+			if preflight {
+				c.Abort()
+				c.Status(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		header.Del("Allow")
+		c.Abort()
+		c.Status(http.StatusNoContent)
+	}
+}
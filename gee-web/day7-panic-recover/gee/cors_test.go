@@ -0,0 +1,86 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func corsRequest(r *Engine, method, path, origin string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{
+		AllowOrigins:       []string{"https://app.example.com", "https://*.geektutu.com"},
+		AllowOriginRegexps: []string{`http://localhost:\d+`},
+		AllowCredentials:   true,
+		MaxAge:             12 * time.Hour,
+		ExposeHeaders:      []string{"X-Total-Count"},
+	}))
+	r.GET("/users", func(c *Context) { c.String(http.StatusOK, "users") })
+
+	for _, path := range []string{"/users", "/unknown"} {
+		w := corsRequest(r, "OPTIONS", path, "https://blog.geektutu.com",
+			"Access-Control-Request-Method", "POST", "Access-Control-Request-Headers", "Content-Type, X-Token")
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("preflight of %s should be 204, got %d", path, w.Code)
+		}
+		h := w.Header()
+		if h.Get("Access-Control-Allow-Origin") != "https://blog.geektutu.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+			h.Get("Access-Control-Allow-Headers") != "Content-Type, X-Token" || h.Get("Access-Control-Max-Age") != "43200" ||
+			!strings.Contains(h.Get("Access-Control-Allow-Methods"), "POST") {
+			t.Fatalf("unexpected preflight headers %v", h)
+		}
+	}
+
+	w := corsRequest(r, "GET", "/users", "http://localhost:3000")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	w = corsRequest(r, "OPTIONS", "/users", "https://evil.com", "Access-Control-Request-Method", "GET")
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed preflight should be 403, got %d %v", w.Code, w.Header())
+	}
+	w = corsRequest(r, "GET", "/users", "https://geektutu.com.evil.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed origin shouldn't get CORS headers, got %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("plain OPTIONS should reach NoMethod, got %d", w.Code)
+	}
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{AllowOrigins: []string{"*"}}))
+	r.POST("/items", func(c *Context) { c.String(http.StatusCreated, "created") })
+	w := corsRequest(r, "POST", "/items", "https://any.example.org")
+	if w.Code != http.StatusCreated || w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "Origin" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSAllowAllWithCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CORS should panic when all origins are allowed with credentials")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
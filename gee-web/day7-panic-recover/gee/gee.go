@@ -4,8 +4,11 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gee/render"
 )
 
 // HandlerFunc defines the request handler used by gee
@@ -21,19 +24,79 @@ type (
 	}
 
 	Engine struct {
+		active int64 // handler chains in progress, first for atomic alignment
 		*RouterGroup
-		router        *router
-		groups        []*RouterGroup     // store all groups
-		htmlTemplates *template.Template // for html render
-		funcMap       template.FuncMap   // for html render
+		router *router
+		pool   sync.Pool // of *Context, see allocateContext
+
+		// HTMLRender renders Context.HTML, it is set by the LoadHTML methods
+		// or to plug in another template engine
+		HTMLRender render.HTMLRender
+		// DebugTemplates makes the LoadHTML methods called afterwards parse
+		// the templates again on every render, to edit them without restarting
+		DebugTemplates bool
+		htmlMu         sync.RWMutex
+		htmlLoader     func(t *template.Template) (*template.Template, error)
+		htmlErr        error            // of the templates which didn't parse
+		funcMap        template.FuncMap // for html render
+
+		// ForwardedByClientIP makes Context.ClientIP trust the
+		// X-Forwarded-For and X-Real-IP headers, only set it behind a proxy
+		ForwardedByClientIP bool
+
+		// RedirectTrailingSlash redirects /foo/ to /foo, or /foo to /foo/,
+		// when only the other one is registered, true by default
+		RedirectTrailingSlash bool
+		// RedirectFixedPath redirects a path which doesn't match, after
+		// removing .. and repeated slashes and with a case-insensitive
+		// lookup, to the registered route found
+		RedirectFixedPath bool
+		// UseRawPath matches the routes against the escaped path, so that
+		// an encoded '/' can be part of a param value
+		UseRawPath bool
+		// UnescapePathValues unescapes the params matched on the escaped
+		// path with UseRawPath, true by default
+		UnescapePathValues bool
+
+		// MaxMultipartMemory is the memory used to parse a multipart form,
+		// the larger files are stored on disk, 32MB by default
+		MaxMultipartMemory int64
+
+		// UseH2C accepts cleartext HTTP/2 (h2c) next to HTTP/1 in Run,
+		// for clients such as internal proxies which know the server speaks it.
+		// It relies on http.Protocols, hence the go 1.24 of go.mod.
+		UseH2C bool
+
+		// timeouts of the servers started by the Run methods, 0 means none
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+
+		mu           sync.Mutex
+		servers      []*http.Server // started by the Run methods
+		onShutdown   []func()
+		shuttingDown bool
+		websockets   map[*WSConn]struct{} // closed with CloseGoingAway by Shutdown
+
+		routeNames  map[string]string // "METHOD pattern" -> name
+		namedRoutes map[string]string // name -> pattern
 	}
 )
 
 // New is the constructor of gee.Engine
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:                newRouter(),
+		RedirectTrailingSlash: true,
+		UnescapePathValues:    true,
+		MaxMultipartMemory:    defaultMultipartMemory,
+	}
 	engine.RouterGroup = &RouterGroup{engine: engine}
-	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return engine.allocateContext()
+	}
+	engine.rebuildErrorHandlers()
 	return engine
 }
 
@@ -53,78 +116,130 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 		parent: group,
 		engine: engine,
 	}
-	engine.groups = append(engine.groups, newGroup)
 	return newGroup
 }
 
-// Use is defined to add middleware to the group
+// Use is defined to add middleware to the group,
+// it only affects the routes registered after it
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// Use adds global middlewares, they also run before NoRoute and NoMethod handlers
+func (engine *Engine) Use(middlewares ...HandlerFunc) {
+	engine.RouterGroup.Use(middlewares...)
+	engine.rebuildErrorHandlers()
+}
+
+// combineHandlers returns the middlewares of the group and all its parents,
+// outermost first, followed by handlers
+func (group *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	var middlewares []HandlerFunc
+	if group.parent != nil {
+		middlewares = group.parent.combineHandlers(group.middlewares)
+	} else {
+		middlewares = group.middlewares
+	}
+	merged := make([]HandlerFunc, 0, len(middlewares)+len(handlers))
+	merged = append(merged, middlewares...)
+	return append(merged, handlers...)
+}
+
+// addRoute registers the handler chain of a route, the handlers run
+// after the group middlewares, in the order they are given
+func (group *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) *Route {
+	if len(handlers) == 0 {
+		panic("gee: there must be at least one handler for route " + method + " " + group.prefix + comp)
+	}
 	pattern := group.prefix + comp
 	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	group.engine.router.addRoute(method, pattern, group.combineHandlers(handlers))
+	return &Route{engine: group.engine, methods: []string{method}, pattern: pattern}
+}
+
+// anyMethods are the methods registered by Any
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "HEAD", "OPTIONS", "DELETE", "CONNECT", "TRACE"}
+
+// Handle registers the handlers for the given method and pattern
+func (group *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute(method, pattern, handlers)
+}
+
+// Any registers the handlers for all the methods in anyMethods
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) *Route {
+	route := &Route{engine: group.engine, methods: anyMethods, pattern: group.prefix + pattern}
+	for _, method := range anyMethods {
+		group.addRoute(method, pattern, handlers)
+	}
+	return route
 }
 
 // GET defines the method to add GET request
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("GET", pattern, handlers)
 }
 
 // POST defines the method to add POST request
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
-}
-
-// create static handler
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filepath")
-		// Check if file exists and/or if we have permission to access it
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
-
-		fileServer.ServeHTTP(c.Writer, c.Req)
-	}
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("POST", pattern, handlers)
+}
+
+// PUT defines the method to add PUT request
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PUT", pattern, handlers)
+}
+
+// PATCH defines the method to add PATCH request
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PATCH", pattern, handlers)
+}
+
+// DELETE defines the method to add DELETE request
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("DELETE", pattern, handlers)
+}
+
+// HEAD defines the method to add HEAD request
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("HEAD", pattern, handlers)
 }
 
-// serve static files
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// Register GET handlers
-	group.GET(urlPattern, handler)
+// OPTIONS defines the method to add OPTIONS request
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("OPTIONS", pattern, handlers)
 }
 
-// for custom render function
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
+// NoRoute sets the handlers called when no route matches the request,
+// they run after the group middlewares like a normal route
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.router.noRoute = handlers
+	engine.rebuildErrorHandlers()
 }
 
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// NoMethod sets the handlers called when the path is registered
+// but not under the request method
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.router.noMethod = handlers
+	engine.rebuildErrorHandlers()
 }
 
-// Run defines the method to start a http server
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+func (engine *Engine) rebuildErrorHandlers() {
+	engine.router.allNoRoute = engine.combineHandlers(engine.router.noRoute)
+	engine.router.allNoMethod = engine.combineHandlers(engine.router.noMethod)
+}
+
+// allocateContext returns a Context for the pool, with room for the
+// params of the longest route registered so far
+func (engine *Engine) allocateContext() *Context {
+	return &Context{Params: make(Params, 0, engine.router.maxParams), engine: engine}
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-	}
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
+	atomic.AddInt64(&engine.active, 1)
+	defer atomic.AddInt64(&engine.active, -1)
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
 	engine.router.handle(c)
+	c.Writer.WriteHeaderNow()
+	engine.pool.Put(c)
 }
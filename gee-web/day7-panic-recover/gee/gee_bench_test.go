@@ -0,0 +1,71 @@
+//go:build !race
+
+package gee
+
+import (
+	"net/http"
+	"testing"
+)
+
+// benchWriter is a ResponseWriter discarding the response without allocating
+type benchWriter struct {
+	header http.Header
+}
+
+func (w *benchWriter) Header() http.Header         { return w.header }
+func (w *benchWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *benchWriter) WriteHeader(int)             {}
+
+var benchBody = []byte("hello")
+
+func newBenchEngine() *Engine {
+	r := New()
+	handler := func(c *Context) {
+		if c.Param("id") == "missing" {
+			c.Status(http.StatusNotFound)
+		}
+		c.Writer.Write(benchBody)
+	}
+	r.Use(func(c *Context) { c.Next() })
+	r.GET("/", handler)
+	r.GET("/users/:id", handler)
+	r.GET("/repos/:owner/:repo/issues/:number", handler)
+	r.GET("/static/*filepath", handler)
+	return r
+}
+
+func benchRequest(r *Engine, path string) func() {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := &benchWriter{header: make(http.Header)}
+	return func() {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func TestServeHTTPZeroAllocs(t *testing.T) {
+	r := newBenchEngine()
+	for _, path := range []string{"/", "/users/42", "/repos/geektutu/7days-golang/issues/7", "/static/css/app.css"} {
+		if allocs := testing.AllocsPerRun(100, benchRequest(r, path)); allocs != 0 {
+			t.Fatalf("GET %s allocates %v times per request", path, allocs)
+		}
+	}
+}
+
+func benchmarkServeHTTP(b *testing.B, path string) {
+	serve := benchRequest(newBenchEngine(), path)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serve()
+	}
+}
+
+func BenchmarkServeStatic(b *testing.B) { benchmarkServeHTTP(b, "/") }
+
+func BenchmarkServeParam(b *testing.B) { benchmarkServeHTTP(b, "/users/42") }
+
+func BenchmarkServeParams(b *testing.B) {
+	benchmarkServeHTTP(b, "/repos/geektutu/7days-golang/issues/7")
+}
+
+func BenchmarkServeCatchAll(b *testing.B) { benchmarkServeHTTP(b, "/static/css/app.css") }
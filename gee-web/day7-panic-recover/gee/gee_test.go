@@ -1,6 +1,11 @@
 package gee
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
 
 func TestNestedGroup(t *testing.T) {
 	r := New()
@@ -14,3 +19,127 @@ func TestNestedGroup(t *testing.T) {
 		t.Fatal("v2 prefix should be /v1/v2")
 	}
 }
+
+func TestRouteHandlerChain(t *testing.T) {
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	r := New()
+	r.Use(mark("engine"))
+	v1 := r.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/hello", mark("auth"), mark("validate"), func(c *Context) {
+		trace = append(trace, "handler")
+		c.String(http.StatusOK, "ok")
+	})
+	v1.GET("/secret", func(c *Context) {
+		c.Fail(http.StatusUnauthorized, "unauthorized")
+	}, mark("unreachable"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/v1/hello", nil))
+	want := []string{"engine", "v1", "auth", "validate", "handler"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("handlers run in order %v, want %v", trace, want)
+	}
+
+	trace = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/v1/secret", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status should be 401, got %d", w.Code)
+	}
+	if !reflect.DeepEqual(trace, []string{"engine", "v1"}) {
+		t.Fatalf("Fail should stop the route chain, got %v", trace)
+	}
+}
+
+func TestHTTPMethods(t *testing.T) {
+	r := New()
+	ok := func(c *Context) { c.String(http.StatusOK, c.Method) }
+	r.PUT("/item", ok)
+	r.PATCH("/item", ok)
+	r.DELETE("/item", ok)
+	r.HEAD("/item", ok)
+	r.OPTIONS("/item", ok)
+	r.Handle("PROPFIND", "/item", ok)
+	r.Any("/any", ok)
+
+	for _, method := range []string{"PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "PROPFIND"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/item", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s /item should be 200, got %d", method, w.Code)
+		}
+	}
+	for _, method := range anyMethods {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/any", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s /any should be 200, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	r := New()
+	r.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+	r.PUT("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/hello", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, PUT" {
+		t.Fatalf("POST /hello should be 405 with Allow: GET, PUT, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET /missing should be 404, got %d", w.Code)
+	}
+
+	var global bool
+	r.Use(func(c *Context) {
+		global = true
+		c.Next()
+	})
+	r.NoRoute(func(c *Context) { c.JSON(http.StatusNotFound, H{"message": "not found"}) })
+	r.NoMethod(func(c *Context) { c.JSON(http.StatusMethodNotAllowed, H{"message": "not allowed"}) })
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if !global || w.Code != http.StatusNotFound || w.Body.String() != "{\"message\":\"not found\"}\n" {
+		t.Fatalf("custom NoRoute should run after middlewares, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/hello", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "{\"message\":\"not allowed\"}\n" {
+		t.Fatalf("custom NoMethod should be used, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestGroupMiddlewareBoundary(t *testing.T) {
+	var wrapped bool
+	r := New()
+	v1 := r.Group("/v1")
+	v1.Use(func(c *Context) {
+		wrapped = true
+		c.Next()
+	})
+	v1.GET("/hello", func(c *Context) { c.String(http.StatusOK, "v1") })
+	r.GET("/v10/hello", func(c *Context) { c.String(http.StatusOK, "v10") })
+	r.GET("/v1admin", func(c *Context) { c.String(http.StatusOK, "v1admin") })
+
+	for path, want := range map[string]bool{"/v1/hello": true, "/v10/hello": false, "/v1admin": false, "/v1/missing": false} {
+		wrapped = false
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		if wrapped != want {
+			t.Fatalf("v1 middleware on %s: got %t, want %t", path, wrapped, want)
+		}
+	}
+}
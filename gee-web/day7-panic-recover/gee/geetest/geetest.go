@@ -0,0 +1,225 @@
+// Package geetest sends requests to a gee Engine in-process and checks the
+// responses, for handler tests:
+//
+//	geetest.New(t, engine).POST("/users").
+//		WithHeader("X-Token", "secret").
+//		WithJSON(gee.H{"name": "geektutu"}).
+//		Expect().
+//		Status(http.StatusCreated).
+//		JSON(gee.H{"id": 1, "name": "geektutu"})
+//
+// A failed check reports the error with t.Errorf and the chain goes on.
+// Use gee.CreateTestContext to call a single HandlerFunc without routing.
+package geetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tester builds the requests to a handler, usually a *gee.Engine
+type Tester struct {
+	t       testing.TB
+	handler http.Handler
+}
+
+// New returns a Tester of handler reporting to t
+func New(t testing.TB, handler http.Handler) *Tester {
+	return &Tester{t: t, handler: handler}
+}
+
+// Request is a request being built, send it with Expect
+type Request struct {
+	tester  *Tester
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    io.Reader
+	err     error
+}
+
+// Request starts a request with method to path, which may have a query string
+func (tt *Tester) Request(method string, path string) *Request {
+	return &Request{tester: tt, method: method, path: path, header: make(http.Header), query: make(url.Values)}
+}
+
+func (tt *Tester) GET(path string) *Request     { return tt.Request("GET", path) }
+func (tt *Tester) POST(path string) *Request    { return tt.Request("POST", path) }
+func (tt *Tester) PUT(path string) *Request     { return tt.Request("PUT", path) }
+func (tt *Tester) PATCH(path string) *Request   { return tt.Request("PATCH", path) }
+func (tt *Tester) DELETE(path string) *Request  { return tt.Request("DELETE", path) }
+func (tt *Tester) HEAD(path string) *Request    { return tt.Request("HEAD", path) }
+func (tt *Tester) OPTIONS(path string) *Request { return tt.Request("OPTIONS", path) }
+
+// WithHeader sets a request header
+func (r *Request) WithHeader(key string, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithQuery adds a query parameter
+func (r *Request) WithQuery(key string, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithCookie adds a cookie
+func (r *Request) WithCookie(name string, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// WithBasicAuth sets the Basic Authorization header
+func (r *Request) WithBasicAuth(user string, password string) *Request {
+	req := http.Request{Header: r.header}
+	req.SetBasicAuth(user, password)
+	return r
+}
+
+// WithBody sets the body and its Content-Type
+func (r *Request) WithBody(contentType string, body io.Reader) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// WithJSON sets obj encoded as JSON as the body
+func (r *Request) WithJSON(obj interface{}) *Request {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		r.err = err
+	}
+	return r.WithBody("application/json", bytes.NewReader(data))
+}
+
+// WithForm sets form urlencoded as the body
+func (r *Request) WithForm(form url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+}
+
+// Expect sends the request and returns its response
+func (r *Request) Expect() *Response {
+	t := r.tester.t
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("geetest: %s %s: %v", r.method, r.path, r.err)
+	}
+	req := httptest.NewRequest(r.method, r.path, r.body)
+	if len(r.query) > 0 {
+		query := req.URL.Query()
+		for key, values := range r.query {
+			query[key] = append(query[key], values...)
+		}
+		req.URL.RawQuery = query.Encode()
+		req.RequestURI = req.URL.RequestURI()
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.tester.handler.ServeHTTP(w, req)
+	return &Response{t: t, name: r.method + " " + r.path, Recorder: w}
+}
+
+// Response is the response to a request, its methods check it
+type Response struct {
+	t    testing.TB
+	name string
+	// Recorder holds the raw response
+	Recorder *httptest.ResponseRecorder
+}
+
+func (r *Response) errorf(format string, args ...interface{}) {
+	r.t.Helper()
+	r.t.Errorf("geetest: %s: %s", r.name, fmt.Sprintf(format, args...))
+}
+
+// Status checks the status code
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.errorf("status is %d, want %d, body %q", r.Recorder.Code, code, r.Recorder.Body.String())
+	}
+	return r
+}
+
+// Header checks the value of a response header
+func (r *Response) Header(key string, value string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.errorf("header %s is %q, want %q", key, got, value)
+	}
+	return r
+}
+
+// Body checks the whole body
+func (r *Response) Body(body string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Body.String(); got != body {
+		r.errorf("body is %q, want %q", got, body)
+	}
+	return r
+}
+
+// BodyContains checks that the body contains s
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Body.String(); !strings.Contains(got, s) {
+		r.errorf("body %q doesn't contain %q", got, s)
+	}
+	return r
+}
+
+// JSON checks that the body is the JSON encoding of expected, whatever the
+// order of the keys and the Go types used, e.g. gee.H or a struct
+func (r *Response) JSON(expected interface{}) *Response {
+	r.t.Helper()
+	var got interface{}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &got); err != nil {
+		r.errorf("body %q is not JSON: %v", r.Recorder.Body.String(), err)
+		return r
+	}
+	data, err := json.Marshal(expected)
+	if err != nil {
+		r.errorf("can't encode the expected value: %v", err)
+		return r
+	}
+	var want interface{}
+	json.Unmarshal(data, &want)
+	if !reflect.DeepEqual(got, want) {
+		r.errorf("JSON body is %s, want %s", strings.TrimSpace(r.Recorder.Body.String()), data)
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into obj for further checks
+func (r *Response) DecodeJSON(obj interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), obj); err != nil {
+		r.errorf("can't decode the body %q: %v", r.Recorder.Body.String(), err)
+	}
+	return r
+}
+
+// Cookie returns the cookie name set by the response, or nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
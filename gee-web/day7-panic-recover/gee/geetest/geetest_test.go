@@ -0,0 +1,100 @@
+package geetest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gee"
+)
+
+// recorder collects the errors instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newEngine() *gee.Engine {
+	r := gee.New()
+	r.POST("/users", func(c *gee.Context) {
+		var user struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if c.Bind(&user) != nil {
+			return
+		}
+		c.SetHeader("X-Token", c.Req.Header.Get("X-Token"))
+		c.JSON(http.StatusCreated, gee.H{"id": 1, "name": user.Name})
+	})
+	r.GET("/search", func(c *gee.Context) {
+		token, _ := c.Cookie("token")
+		c.String(http.StatusOK, "q=%s lang=%s token=%s", c.Query("q"), c.Query("lang"), token)
+	})
+	r.POST("/login", func(c *gee.Context) {
+		c.SetCookie("session", c.PostForm("user"), 0, "", "", false, true)
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestTester(t *testing.T) {
+	tt := New(t, newEngine())
+	tt.POST("/users").
+		WithHeader("X-Token", "secret").
+		WithJSON(gee.H{"name": "geektutu"}).
+		Expect().
+		Status(http.StatusCreated).
+		Header("X-Token", "secret").
+		JSON(gee.H{"name": "geektutu", "id": 1})
+
+	tt.GET("/search?q=gee").WithQuery("lang", "go").WithCookie("token", "t1").
+		Expect().Status(http.StatusOK).Body("q=gee lang=go token=t1")
+
+	res := tt.POST("/login").WithForm(url.Values{"user": []string{"geektutu"}}).Expect().Status(http.StatusNoContent)
+	if cookie := res.Cookie("session"); cookie == nil || cookie.Value != "geektutu" || !cookie.HttpOnly {
+		t.Fatalf("unexpected cookie %v", cookie)
+	}
+
+	var body struct{ Message string }
+	tt.POST("/users").WithJSON(gee.H{}).Expect().Status(http.StatusBadRequest).DecodeJSON(&body)
+	if body.Message != "validation failed" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+}
+
+func TestTesterReportsFailures(t *testing.T) {
+	rec := &recorder{TB: t}
+	New(rec, newEngine()).POST("/users").WithJSON(gee.H{"name": "gee"}).Expect().
+		Status(http.StatusOK).
+		Header("X-Token", "secret").
+		JSON(gee.H{"id": 2, "name": "gee"}).
+		BodyContains("geektutu")
+	if len(rec.errors) != 4 {
+		t.Fatalf("expected 4 failures, got %q", rec.errors)
+	}
+	if !strings.Contains(rec.errors[0], "POST /users: status is 201, want 200") {
+		t.Fatalf("unexpected message %q", rec.errors[0])
+	}
+}
+
+func TestCreateTestContext(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gee.CreateTestContext(w, httptest.NewRequest("GET", "/hello?name=gee", nil))
+	c.Params = gee.Params{{Key: "id", Value: "7"}}
+	handler := func(c *gee.Context) {
+		c.String(http.StatusOK, "%s %s", c.Param("id"), c.Query("name"))
+	}
+	handler(c)
+	if w.Code != http.StatusOK || w.Body.String() != "7 gee" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,95 @@
+package gee
+
+import (
+	"html/template"
+	"io/fs"
+	"regexp"
+
+	"gee/render"
+)
+
+// SetFuncMap sets the funcs of the templates, it may be called before or
+// after the LoadHTML methods: templates already loaded are parsed again.
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.htmlMu.Lock()
+	defer engine.htmlMu.Unlock()
+	engine.funcMap = funcMap
+	if engine.htmlLoader != nil {
+		engine.setHTMLRender()
+	}
+}
+
+// LoadHTMLGlob loads the templates matching pattern
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	engine.loadHTML(func(t *template.Template) (*template.Template, error) {
+		return t.ParseGlob(pattern)
+	})
+}
+
+// LoadHTMLFiles loads the template files
+func (engine *Engine) LoadHTMLFiles(files ...string) {
+	engine.loadHTML(func(t *template.Template) (*template.Template, error) {
+		return t.ParseFiles(files...)
+	})
+}
+
+// LoadHTMLFS loads the templates of fsys matching patterns, such as an embed.FS
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.loadHTML(func(t *template.Template) (*template.Template, error) {
+		return t.ParseFS(fsys, patterns...)
+	})
+}
+
+// loadHTML parses the templates with loader and panics if they don't parse.
+// A call to a function not defined yet only fails the start of the server
+// or the render, since SetFuncMap may still define it. With DebugTemplates
+// the templates are parsed on every render instead.
+func (engine *Engine) loadHTML(loader func(t *template.Template) (*template.Template, error)) {
+	engine.htmlMu.Lock()
+	defer engine.htmlMu.Unlock()
+	engine.htmlLoader = loader
+	if err := engine.setHTMLRender(); err != nil && !undefinedFunc.MatchString(err.Error()) {
+		panic(err)
+	}
+}
+
+// undefinedFunc matches the parse errors of calls to unknown functions
+var undefinedFunc = regexp.MustCompile(`function ".*" not defined`)
+
+// setHTMLRender sets HTMLRender from htmlLoader, htmlMu must be held. When
+// the templates don't parse the error is kept for htmlRender.
+func (engine *Engine) setHTMLRender() error {
+	loader, funcMap := engine.htmlLoader, engine.funcMap
+	load := func() (*template.Template, error) {
+		return loader(template.New("").Funcs(funcMap))
+	}
+	engine.HTMLRender, engine.htmlErr = nil, nil
+	if engine.DebugTemplates {
+		engine.HTMLRender = render.HTMLDebug{Load: load}
+		return nil
+	}
+	t, err := load()
+	if err != nil {
+		engine.htmlErr = err
+		return err
+	}
+	engine.HTMLRender = render.HTMLProduction{Template: t}
+	return nil
+}
+
+// htmlRender returns the HTMLRender of the engine, or why the loaded
+// templates didn't parse
+func (engine *Engine) htmlRender() (render.HTMLRender, error) {
+	engine.htmlMu.RLock()
+	defer engine.htmlMu.RUnlock()
+	return engine.HTMLRender, engine.htmlErr
+}
+
+// SetHTMLRender makes the routes of the group registered afterwards render
+// Context.HTML with r, such as a render.HTMLLayouts of their own
+func (group *RouterGroup) SetHTMLRender(r render.HTMLRender) {
+	group.Use(func(c *Context) {
+		c.htmlRender = r
+		c.Next()
+	})
+}
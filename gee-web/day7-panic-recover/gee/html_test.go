@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gee/render"
+)
+
+func htmlRequest(r *Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestLoadHTMLFS(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(fstest.MapFS{"templates/hello.tmpl": {Data: []byte(`hello {{shout .}}`)}}, "templates/*.tmpl")
+	// the templates are parsed again, so the funcs may come afterwards
+	r.SetFuncMap(template.FuncMap{"shout": strings.ToUpper})
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "hello.tmpl", "gee") })
+
+	w := htmlRequest(r, "/")
+	if w.Code != http.StatusOK || w.Body.String() != "hello GEE" || w.Header().Get("Content-Type") != "text/html" {
+		t.Fatalf("unexpected response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestLoadHTMLBroken(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("LoadHTMLFS should panic on a broken template")
+		}
+	}()
+	New().LoadHTMLFS(fstest.MapFS{"index.tmpl": {Data: []byte(`{{`)}}, "*.tmpl")
+}
+
+func TestLoadHTMLMissingFunc(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(fstest.MapFS{"index.tmpl": {Data: []byte(`{{shout .}}`)}}, "*.tmpl")
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.tmpl", "gee") })
+	if w := htmlRequest(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("a template calling an undefined func should answer 500, got %d", w.Code)
+	}
+	if _, err := r.newServer(":0"); err == nil {
+		t.Fatal("a template calling an undefined func should fail the start of the server")
+	}
+}
+
+func TestHTMLDebugReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.tmpl")
+	if err := os.WriteFile(file, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.DebugTemplates = true
+	r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.tmpl", nil) })
+
+	if w := htmlRequest(r, "/"); w.Body.String() != "v1" {
+		t.Fatalf("expected v1, got %q", w.Body.String())
+	}
+	if err := os.WriteFile(file, []byte("v2 {{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w := htmlRequest(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("a broken template should answer 500, got %d", w.Code)
+	}
+	if err := os.WriteFile(file, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w := htmlRequest(r, "/"); w.Body.String() != "v2" {
+		t.Fatalf("debug mode should reload the template, got %q", w.Body.String())
+	}
+}
+
+type upperHTMLRender struct{}
+
+func (upperHTMLRender) Instance(name string, data interface{}) render.Render {
+	return render.Data{ContentType: "text/html", Data: []byte(strings.ToUpper(name))}
+}
+
+func TestGroupHTMLRender(t *testing.T) {
+	r := New()
+	r.HTMLRender = render.HTMLProduction{Template: template.Must(template.New("page").Parse("site"))}
+	admin := r.Group("/admin")
+	admin.SetHTMLRender(upperHTMLRender{})
+	admin.GET("/page", func(c *Context) { c.HTML(http.StatusOK, "page", nil) })
+	r.GET("/page", func(c *Context) { c.HTML(http.StatusOK, "page", nil) })
+
+	if w := htmlRequest(r, "/admin/page"); w.Body.String() != "PAGE" {
+		t.Fatalf("group should use its own render, got %q", w.Body.String())
+	}
+	if w := htmlRequest(r, "/page"); w.Body.String() != "site" {
+		t.Fatalf("engine render expected, got %q", w.Body.String())
+	}
+}
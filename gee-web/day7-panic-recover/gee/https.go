@@ -0,0 +1,61 @@
+package gee
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HTTPSRedirect redirects plain HTTP requests to https at httpsAddr, the
+// port defaults to 443. If httpsAddr has a host, e.g. "example.com:8443",
+// it is always the target. Otherwise, e.g. ":8443", the host of the request
+// is kept when it is one of hosts and other hosts are answered 400, since
+// the client controls the Host header. It panics if there is no host at all.
+// GET and HEAD requests get 301, the others 308 to keep their method and body.
+// X-Forwarded-Proto is trusted when Engine.ForwardedByClientIP is set.
+func HTTPSRedirect(httpsAddr string, hosts ...string) HandlerFunc {
+	canonical, port, err := net.SplitHostPort(httpsAddr)
+	if err != nil {
+		canonical, port = httpsAddr, ""
+	}
+	if canonical == "" && len(hosts) == 0 {
+		panic("gee: HTTPSRedirect needs the host of httpsAddr or the allowed hosts")
+	}
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[strings.ToLower(host)] = true
+	}
+	return func(c *Context) {
+		if isHTTPS(c) {
+			c.Next()
+			return
+		}
+		host := canonical
+		if host == "" {
+			host = c.Req.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if !allowed[strings.ToLower(host)] {
+				c.Fail(http.StatusBadRequest, "unknown host")
+				return
+			}
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		code := http.StatusPermanentRedirect
+		if c.Method == "GET" || c.Method == "HEAD" {
+			code = http.StatusMovedPermanently
+		}
+		c.Abort()
+		c.Redirect(code, "https://"+host+c.Req.URL.RequestURI())
+	}
+}
+
+func isHTTPS(c *Context) bool {
+	if c.Req.TLS != nil {
+		return true
+	}
+	return c.engine != nil && c.engine.ForwardedByClientIP && c.Req.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,204 @@
+package gee
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSignedCert writes a certificate for 127.0.0.1 and its key in dir
+func selfSignedCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"gee"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// getWithRetry waits for the server started in the background to listen
+func getWithRetry(t *testing.T, client *http.Client, url string) *http.Response {
+	var err error
+	for i := 0; i < 100; i++ {
+		var resp *http.Response
+		if resp, err = client.Get(url); err == nil {
+			return resp
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(err)
+	return nil
+}
+
+func TestRunTLSHTTP2(t *testing.T) {
+	certFile, keyFile, pool := selfSignedCert(t, t.TempDir())
+	r := New()
+	var canPush bool
+	r.GET("/", func(c *Context) {
+		// the Go client disables push, so only check the writer exposes it
+		canPush = c.Writer.Pusher() != nil
+		c.String(http.StatusOK, c.Req.Proto)
+	})
+	addr := freeAddr(t)
+	go r.RunTLS(addr, certFile, keyFile)
+	defer r.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	resp := getWithRetry(t, client, "https://"+addr+"/")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2 over TLS, got %s %q", resp.Proto, body)
+	}
+	if !canPush {
+		t.Fatal("HTTP/2 writer should support Push")
+	}
+	client.CloseIdleConnections()
+}
+
+func TestH2C(t *testing.T) {
+	r := New()
+	r.UseH2C = true
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.Req.Proto)
+	})
+	addr := freeAddr(t)
+	go r.Run(addr)
+	defer r.Shutdown(context.Background())
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	resp := getWithRetry(t, client, "http://"+addr+"/")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Fatalf("expected cleartext HTTP/2, got %s %q", resp.Proto, body)
+	}
+	client.CloseIdleConnections()
+
+	resp = getWithRetry(t, http.DefaultClient, "http://"+addr+"/")
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 {
+		t.Fatalf("HTTP/1 should still be served, got %s", resp.Proto)
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	r := New()
+	r.Use(HTTPSRedirect(":8443", "example.com"))
+	r.GET("/login", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com:8080/login?next=/", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com:8443/login?next=/" {
+		t.Fatalf("unexpected redirect %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "http://example.com/missing", nil))
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "https://example.com:8443/missing" {
+		t.Fatalf("unexpected redirect %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://evil.com/login", nil))
+	if w.Code != http.StatusBadRequest || w.Header().Get("Location") != "" {
+		t.Fatalf("unknown hosts shouldn't be redirected, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "https://example.com/login", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("https request should pass, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/login", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("X-Forwarded-Proto shouldn't be trusted by default, got %d", w.Code)
+	}
+	r.ForwardedByClientIP = true
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("X-Forwarded-Proto should be trusted behind a proxy, got %d", w.Code)
+	}
+}
+
+func TestHTTPSRedirectCanonicalHost(t *testing.T) {
+	r := New()
+	r.Use(HTTPSRedirect("example.com"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://evil.com/login", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/login" {
+		t.Fatalf("unexpected redirect %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("HTTPSRedirect without any host should panic")
+		}
+	}()
+	HTTPSRedirect(":443")
+}
+
+func TestPushNotSupported(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err := c.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -1,7 +1,12 @@
 package gee
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"sync"
 	"time"
 )
 
@@ -12,6 +17,184 @@ func Logger() HandlerFunc {
 		// Process request
 		c.Next()
 		// Calculate resolution time
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		log.Printf("[%d] %s in %v", c.Writer.Status(), c.Req.RequestURI, time.Since(t))
+	}
+}
+
+// LogFormatterParams is the information about a request given to a LogFormatter
+type LogFormatterParams struct {
+	TimeStamp  time.Time     `json:"time"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	ClientIP   string        `json:"client_ip"`
+	Latency    time.Duration `json:"latency_ns"`
+	StatusCode int           `json:"status"`
+	BodySize   int           `json:"bytes"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	// colored tells if the formatter may use ANSI colors
+	colored bool
+}
+
+// IsOutputColor reports whether the formatter may use ANSI colors, as set
+// by ForceColor, DisableColor or the detection of a terminal
+func (p LogFormatterParams) IsOutputColor() bool {
+	return p.colored
+}
+
+// LogFormatter returns the line written for a request
+type LogFormatter func(params LogFormatterParams) string
+
+// LoggerConfig configures LoggerWithConfig
+type LoggerConfig struct {
+	// Output is where the lines are written, os.Stdout by default
+	Output io.Writer
+	// Formatter builds the lines, defaultLogFormatter by default
+	Formatter LogFormatter
+	// JSON writes one JSON object per request, Formatter is ignored then
+	JSON bool
+	// SkipPaths are the request paths which are not logged, e.g. health checks
+	SkipPaths []string
+	// RequestIDHeader is read from the request, or the response, "X-Request-ID" by default
+	RequestIDHeader string
+	// ForceColor colors the output even if it isn't a terminal,
+	// DisableColor never colors it
+	ForceColor   bool
+	DisableColor bool
+}
+
+const (
+	green   = "\033[97;42m"
+	white   = "\033[90;47m"
+	yellow  = "\033[90;43m"
+	red     = "\033[97;41m"
+	blue    = "\033[97;44m"
+	magenta = "\033[97;45m"
+	cyan    = "\033[97;46m"
+	reset   = "\033[0m"
+)
+
+func statusColor(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return green
+	case code >= 300 && code < 400:
+		return white
+	case code >= 400 && code < 500:
+		return yellow
+	}
+	return red
+}
+
+func methodColor(method string) string {
+	switch method {
+	case "GET":
+		return blue
+	case "POST":
+		return cyan
+	case "PUT", "PATCH":
+		return yellow
+	case "DELETE":
+		return red
+	case "HEAD":
+		return magenta
+	}
+	return reset
+}
+
+// defaultLogFormatter writes
+// [GEE] 2006/01/02 - 15:04:05 | 200 |   1.2ms |  127.0.0.1 | GET     "/path" | 12B | request-id | user-agent
+func defaultLogFormatter(p LogFormatterParams) string {
+	status, method, resetColor := "", "", ""
+	if p.IsOutputColor() {
+		status, method, resetColor = statusColor(p.StatusCode), methodColor(p.Method), reset
+	}
+	return fmt.Sprintf("[GEE] %s |%s %3d %s| %10v | %15s |%s %-7s %s %q | %dB | %s | %s\n",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		status, p.StatusCode, resetColor,
+		p.Latency,
+		p.ClientIP,
+		method, p.Method, resetColor,
+		p.Path,
+		p.BodySize,
+		p.RequestID,
+		p.UserAgent,
+	)
+}
+
+// isTerminal reports whether w is a character device, such as a console
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// LoggerWithConfig returns an access logger, it reads the status and the size
+// from c.Writer so it sees responses written by any means
+func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	formatter := config.Formatter
+	if formatter == nil {
+		formatter = defaultLogFormatter
+	}
+	requestIDHeader := config.RequestIDHeader
+	if requestIDHeader == "" {
+		requestIDHeader = "X-Request-ID"
+	}
+	colored := !config.DisableColor && (config.ForceColor || isTerminal(out))
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+	var mu sync.Mutex
+
+	return func(c *Context) {
+		start := time.Now()
+		path := c.Req.URL.Path
+		raw := c.Req.URL.RawQuery
+
+		c.Next()
+
+		if skip[path] {
+			return
+		}
+		if raw != "" {
+			path = path + "?" + raw
+		}
+		params := LogFormatterParams{
+			TimeStamp:  time.Now(),
+			Method:     c.Method,
+			Path:       path,
+			ClientIP:   c.ClientIP(),
+			StatusCode: c.Writer.Status(),
+			BodySize:   c.Writer.Size(),
+			UserAgent:  c.Req.UserAgent(),
+			RequestID:  c.Req.Header.Get(requestIDHeader),
+			colored:    colored,
+		}
+		params.Latency = params.TimeStamp.Sub(start)
+		if params.BodySize < 0 {
+			params.BodySize = 0
+		}
+		if params.RequestID == "" {
+			params.RequestID = c.Writer.Header().Get(requestIDHeader)
+		}
+
+		var line []byte
+		if config.JSON {
+			line, _ = json.Marshal(params)
+			line = append(line, '\n')
+		} else {
+			line = []byte(formatter(params))
+		}
+		mu.Lock()
+		out.Write(line)
+		mu.Unlock()
 	}
 }
@@ -0,0 +1,86 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerWithConfig(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{Output: &buf, SkipPaths: []string{"/health"}}))
+	r.GET("/raw", func(c *Context) {
+		c.Writer.WriteHeader(http.StatusAccepted)
+		c.Writer.Write([]byte("accepted"))
+	})
+	r.GET("/health", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest("GET", "/raw?x=1", nil)
+	req.Header.Set("User-Agent", "gee-test")
+	req.Header.Set("X-Request-ID", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	line := buf.String()
+	for _, want := range []string{"[GEE]", "| 202 |", "192.0.2.1", `GET      "/raw?x=1"`, "8B", "req-1", "gee-test"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("log line %q should contain %q", line, want)
+		}
+	}
+	if strings.Count(line, "\n") != 1 || strings.Contains(line, "\033[") {
+		t.Fatalf("expected one uncolored line, got %q", line)
+	}
+}
+
+func TestLoggerColor(t *testing.T) {
+	var buf bytes.Buffer
+	var colored []bool
+	formatter := func(p LogFormatterParams) string {
+		colored = append(colored, p.IsOutputColor())
+		return defaultLogFormatter(p)
+	}
+	for _, config := range []LoggerConfig{
+		{Output: &buf, Formatter: formatter},
+		{Output: &buf, Formatter: formatter, ForceColor: true},
+		{Output: &buf, Formatter: formatter, ForceColor: true, DisableColor: true},
+	} {
+		r := New()
+		r.Use(LoggerWithConfig(config))
+		r.GET("/", func(c *Context) {})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if len(colored) != 3 || colored[0] || !colored[1] || colored[2] {
+		t.Fatalf("unexpected colors %v", colored)
+	}
+	if !strings.Contains(buf.String(), green) {
+		t.Fatalf("ForceColor should color the default format, got %q", buf.String())
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.ForwardedByClientIP = true
+	r.Use(LoggerWithConfig(LoggerConfig{Output: &buf, JSON: true}))
+	r.GET("/user", func(c *Context) {
+		c.Writer.Header().Set("X-Request-ID", "generated")
+		c.JSON(http.StatusOK, H{"name": "geektutu"})
+	})
+
+	req := httptest.NewRequest("GET", "/user", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var params LogFormatterParams
+	if err := json.Unmarshal(buf.Bytes(), &params); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", buf.String(), err)
+	}
+	if params.Method != "GET" || params.Path != "/user" || params.StatusCode != 200 ||
+		params.ClientIP != "203.0.113.7" || params.BodySize != 20 || params.RequestID != "generated" {
+		t.Fatalf("unexpected log entry %+v", params)
+	}
+}
@@ -0,0 +1,149 @@
+package gee
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// defaultMultipartMemory is the default of Engine.MaxMultipartMemory
+const defaultMultipartMemory = 32 << 20
+
+// MultipartForm parses the multipart body, keeping at most
+// Engine.MaxMultipartMemory of the files in memory and the rest on disk
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+			return nil, err
+		}
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile returns the first file of the multipart field name
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile writes file to dst, creating its directory if needed
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return saveFile(src, dst)
+}
+
+// saveFile copies r to dst, which is removed if the copy fails
+func saveFile(r io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// MultipartReader returns a reader of the parts of a multipart body,
+// to process them as they arrive instead of parsing the whole form.
+// It can't be used with MultipartForm, FormFile, PostForm or binding.
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.Req.MultipartReader()
+}
+
+// StreamMultipart calls fn with every part of the multipart body, in order,
+// without buffering the files. The part can only be read until fn returns,
+// an error of fn stops the stream and is returned.
+//
+//	err := c.StreamMultipart(func(part *multipart.Part) error {
+//		if part.FileName() == "" {
+//			return nil // a plain field
+//		}
+//		return importCSV(part)
+//	})
+func (c *Context) StreamMultipart(fn func(part *multipart.Part) error) error {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SavePart streams the file of part to dst and returns its size,
+// for StreamMultipart
+func SavePart(part *multipart.Part, dst string) (int64, error) {
+	counter := &countingReader{r: part}
+	err := saveFile(counter, dst)
+	return counter.n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// BodyLimit answers 413 to the requests whose body is larger than n bytes.
+// A body without Content-Length is cut at n bytes, reading more fails with
+// a *http.MaxBytesError, which Bind answers with 413 too.
+func BodyLimit(n int64) HandlerFunc {
+	if n <= 0 {
+		panic("gee: BodyLimit requires a positive limit")
+	}
+	return func(c *Context) {
+		if c.Req.ContentLength > n {
+			c.Fail(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", n))
+			return
+		}
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, n)
+		}
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err comes from reading past BodyLimit
+func IsBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
@@ -0,0 +1,142 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// multipartBody returns a body with the field name=geektutu and files
+// uploaded under "file", and its Content-Type
+func multipartBody(t *testing.T, files map[string]string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "geektutu")
+	for filename, content := range files {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func uploadRequest(r *Engine, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestFormFile(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.MaxMultipartMemory = 8
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.SaveUploadedFile(file, filepath.Join(dir, "uploads", file.Filename)); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		form, _ := c.MultipartForm()
+		c.String(http.StatusOK, "%s %s %d", c.PostForm("name"), file.Filename, len(form.File["file"]))
+	})
+
+	body, contentType := multipartBody(t, map[string]string{"report.csv": "id,name\n1,gee\n"})
+	w := uploadRequest(r, "/upload", body, contentType)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu report.csv 1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "uploads", "report.csv"))
+	if err != nil || string(data) != "id,name\n1,gee\n" {
+		t.Fatalf("unexpected saved file %q %v", data, err)
+	}
+
+	body, contentType = multipartBody(t, nil)
+	if w := uploadRequest(r, "/upload", body, contentType); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should be 400, got %d", w.Code)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.POST("/import", func(c *Context) {
+		var fields, sizes []string
+		err := c.StreamMultipart(func(part *multipart.Part) error {
+			if part.FileName() == "" {
+				value, _ := io.ReadAll(part)
+				fields = append(fields, part.FormName()+"="+string(value))
+				return nil
+			}
+			n, err := SavePart(part, filepath.Join(dir, part.FileName()))
+			sizes = append(sizes, part.FileName()+":"+strings.Repeat("#", int(n)))
+			return err
+		})
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %s", strings.Join(fields, ","), strings.Join(sizes, ","))
+	})
+
+	body, contentType := multipartBody(t, map[string]string{"a.txt": "abc"})
+	w := uploadRequest(r, "/import", body, contentType)
+	if w.Code != http.StatusOK || w.Body.String() != "name=geektutu a.txt:###" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "abc" {
+		t.Fatalf("unexpected streamed file %q", data)
+	}
+	if w := uploadRequest(r, "/import", strings.NewReader("x"), "text/plain"); w.Code != http.StatusBadRequest {
+		t.Fatalf("a body which is not multipart should be 400, got %d", w.Code)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	r := New()
+	limited := r.Group("/limited")
+	limited.Use(BodyLimit(64))
+	limited.POST("/json", func(c *Context) {
+		var obj map[string]interface{}
+		if c.Bind(&obj) == nil {
+			c.String(http.StatusOK, "ok")
+		}
+	})
+	r.POST("/json", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	large := `{"text":"` + strings.Repeat("a", 100) + `"}`
+	if w := uploadRequest(r, "/limited/json", strings.NewReader(`{"a":1}`), "application/json"); w.Code != http.StatusOK {
+		t.Fatalf("small body should pass, got %d", w.Code)
+	}
+	if w := uploadRequest(r, "/limited/json", strings.NewReader(large), "application/json"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body with Content-Length should be 413, got %d", w.Code)
+	}
+
+	// without Content-Length the body is cut while reading
+	req := httptest.NewRequest("POST", "/limited/json", io.MultiReader(strings.NewReader(large)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large streamed body should be 413, got %d %q", w.Code, w.Body.String())
+	}
+
+	if w := uploadRequest(r, "/json", strings.NewReader(large), "application/json"); w.Code != http.StatusOK {
+		t.Fatalf("routes outside the group should not be limited, got %d", w.Code)
+	}
+}
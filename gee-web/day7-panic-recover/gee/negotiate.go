@@ -0,0 +1,118 @@
+package gee
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MIME types understood by Negotiate
+const (
+	MIMEJSON     = "application/json"
+	MIMEHTML     = "text/html"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/x-yaml"
+	MIMEPROTOBUF = "application/x-protobuf"
+)
+
+// Negotiate holds the data of every format a handler can answer with,
+// Data is used when the data of the chosen format is nil. Plain text
+// and protobuf always render Data.
+type Negotiate struct {
+	Offered  []string
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	Data     interface{}
+}
+
+// Negotiate renders the offered format preferred by the Accept header,
+// it fails with 406 when none is acceptable
+func (c *Context) Negotiate(code int, config Negotiate) {
+	pick := func(data interface{}) interface{} {
+		if data == nil {
+			return config.Data
+		}
+		return data
+	}
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, pick(config.HTMLData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEPlain:
+		c.String(code, "%v", config.Data)
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData))
+	case MIMEPROTOBUF:
+		c.ProtoBuf(code, config.Data)
+	default:
+		c.Fail(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+	}
+}
+
+type acceptedType struct {
+	mime string
+	q    float64
+}
+
+// NegotiateFormat returns the offered MIME type preferred by the Accept header,
+// the first offered one without Accept header, or "" if none is acceptable
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	header := c.Req.Header.Get("Accept")
+	if header == "" {
+		return offered[0]
+	}
+
+	var accepted []acceptedType
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if q > 0 {
+			accepted = append(accepted, acceptedType{mime, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	for _, a := range accepted {
+		for _, o := range offered {
+			if matchMIME(a.mime, o) {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// matchMIME reports whether the accepted type, possibly a wildcard, covers offered
+func matchMIME(accepted string, offered string) bool {
+	if accepted == "*/*" || accepted == offered {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offered, accepted[:len(accepted)-1])
+	}
+	return false
+}
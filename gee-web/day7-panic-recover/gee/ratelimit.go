@@ -0,0 +1,158 @@
+package gee
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TokenBucket is a rate limiting policy: a bucket holds up to Burst tokens,
+// refilled at Rate tokens per second, and every request takes one token
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult is the state of a bucket after taking a token
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimitStore keeps the buckets of RateLimit,
+// implement it on a shared backend to limit across several servers
+type RateLimitStore interface {
+	Take(key string, bucket TokenBucket, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	TokenBucket
+	// KeyFunc returns the key of the bucket of a request, the client IP by default
+	KeyFunc func(c *Context) string
+	// Store keeps the buckets, a new MemoryRateLimitStore by default
+	Store RateLimitStore
+}
+
+// KeyByIP keys the buckets by c.ClientIP()
+func KeyByIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// KeyByHeader keys the buckets by the value of a request header,
+// requests without it share the bucket of their client IP
+func KeyByHeader(name string) func(c *Context) string {
+	return func(c *Context) string {
+		if value := c.Req.Header.Get(name); value != "" {
+			return name + ":" + value
+		}
+		return c.ClientIP()
+	}
+}
+
+// RateLimit limits the requests per key with a token bucket.
+// It sets the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// headers, and aborts with 429 and Retry-After when the bucket is empty.
+// Every call has its own store unless one is given, so a group using it
+// gets a budget of its own. When the store fails the request is let through.
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	if config.Rate <= 0 || config.Burst <= 0 {
+		panic("gee: RateLimit requires a positive Rate and Burst")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	limit := strconv.Itoa(config.Burst)
+	return func(c *Context) {
+		result, err := config.Store.Take(config.KeyFunc(c), config.TokenBucket, time.Now())
+		if err != nil {
+			log.Printf("[WARNING] rate limit store: %v", err)
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", limit)
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.Fail(http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket has refilled at its own rate
+}
+
+// MemoryRateLimitStore keeps the buckets in the memory of the process.
+// A bucket that has refilled is the same as a new one, so such buckets
+// are evicted at most once a minute.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucketState
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucketState)}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, bucket TokenBucket, now time.Time) (RateLimitResult, error) {
+	burst := float64(bucket.Burst)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucketState{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*bucket.Rate)
+		b.last = now
+	}
+
+	var result RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = rateDuration(1-b.tokens, bucket.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = rateDuration(burst-b.tokens, bucket.Rate)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// rateDuration is the time needed to refill tokens at rate
+func rateDuration(tokens float64, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
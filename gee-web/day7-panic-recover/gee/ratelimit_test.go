@@ -0,0 +1,76 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Use(RateLimit(RateLimitConfig{TokenBucket: TokenBucket{Rate: 1, Burst: 2}, KeyFunc: KeyByHeader("X-Token")}))
+	api.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+	r.GET("/free", func(c *Context) { c.String(http.StatusOK, "free") })
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("X-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := get("/api/hello", "a")
+		if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != remaining {
+			t.Fatalf("request %d: unexpected %d %v", i, w.Code, w.Header())
+		}
+	}
+	w := get("/api/hello", "a")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After 1, got %d %v", w.Code, w.Header())
+	}
+	if w := get("/api/hello", "b"); w.Code != http.StatusOK {
+		t.Fatalf("another key should have its own bucket, got %d", w.Code)
+	}
+	for i := 0; i < 5; i++ {
+		if w := get("/free", "a"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("routes outside the group should not be limited, got %d", w.Code)
+		}
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	bucket := TokenBucket{Rate: 2, Burst: 1}
+	now := time.Now()
+	if res, _ := s.Take("k", bucket, now); !res.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+	res, _ := s.Take("k", bucket, now.Add(100*time.Millisecond))
+	if res.Allowed || res.RetryAfter != 400*time.Millisecond {
+		t.Fatalf("expected a denial with 400ms to wait, got %+v", res)
+	}
+	if res, _ := s.Take("k", bucket, now.Add(500*time.Millisecond)); !res.Allowed {
+		t.Fatal("bucket should have refilled")
+	}
+
+	s.Take("idle", bucket, now)
+	s.Take("k", bucket, now.Add(2*time.Minute))
+	if len(s.buckets) != 1 {
+		t.Fatalf("refilled buckets should be evicted, %d left", len(s.buckets))
+	}
+
+	// every bucket is evicted at its own rate, not at the rate of the caller
+	s = NewMemoryRateLimitStore()
+	slow := TokenBucket{Rate: 0.001, Burst: 1}
+	s.Take("slow", slow, now)
+	s.Take("fast", bucket, now.Add(2*time.Minute))
+	if res, _ := s.Take("slow", slow, now.Add(2*time.Minute)); res.Allowed {
+		t.Fatal("a slow bucket shouldn't be evicted before it refilled")
+	}
+}
@@ -1,36 +1,117 @@
 package gee
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
+// RecoveryFunc answers a request whose handlers panicked with err
+type RecoveryFunc func(c *Context, err interface{})
+
 // print stack trace for debug
 func trace(message string) string {
-	var pcs [32]uintptr
-	n := runtime.Callers(3, pcs[:]) // skip first 3 caller
+	pcs := make([]uintptr, 32)
+	for {
+		n := runtime.Callers(3, pcs) // skip first 3 caller
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
 
 	var str strings.Builder
 	str.WriteString(message + "\nTraceback:")
-	for _, pc := range pcs[:n] {
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		str.WriteString(fmt.Sprintf("\n\t%s:%d %s", frame.File, frame.Line, frame.Function))
+		if !more {
+			break
+		}
 	}
 	return str.String()
 }
 
+// sensitiveHeaders are masked in the request dumped on panic
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// dumpRequest returns the request line and headers, with credentials masked
+func dumpRequest(req *http.Request) string {
+	dump, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return err.Error()
+	}
+	lines := strings.Split(strings.TrimSpace(string(dump)), "\r\n")
+	for i, line := range lines {
+		for _, header := range sensitiveHeaders {
+			if len(line) > len(header) && strings.EqualFold(line[:len(header)+1], header+":") {
+				lines[i] = header + ": *"
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isBrokenPipe reports whether err comes from writing to a client that went away
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	return errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET)
+}
+
+func defaultRecovery(c *Context, err interface{}) {
+	c.Fail(http.StatusInternalServerError, "Internal Server Error")
+}
+
 func Recovery() HandlerFunc {
+	return RecoveryWithWriter(log.Writer())
+}
+
+// RecoveryWithWriter recovers from panics, logs them to out, unless nil,
+// and calls recovery, which answers 500 by default. Nothing is written when
+// the client connection is broken, and http.ErrAbortHandler is re-panicked.
+// recovery should check c.Writer.Written() before writing.
+func RecoveryWithWriter(out io.Writer, recovery ...RecoveryFunc) HandlerFunc {
+	handle := RecoveryFunc(defaultRecovery)
+	if len(recovery) > 0 && recovery[0] != nil {
+		handle = recovery[0]
+	}
+	var logger *log.Logger
+	if out != nil {
+		logger = log.New(out, "", log.LstdFlags)
+	}
+
 	return func(c *Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				c.Fail(http.StatusInternalServerError, "Internal Server Error")
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			if isBrokenPipe(err) {
+				if logger != nil {
+					logger.Printf("[Recovery] broken connection: %v\n%s\n\n", err, dumpRequest(c.Req))
+				}
+				c.Abort()
+				return
+			}
+			if logger != nil {
+				logger.Printf("[Recovery] panic recovered:\n%s\n%s\n\n", dumpRequest(c.Req), trace(fmt.Sprintf("%v", err)))
 			}
+			c.Abort()
+			handle(c, err)
 		}()
 
 		c.Next()
@@ -0,0 +1,82 @@
+package gee
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecoveryWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var recovered interface{}
+	r := New()
+	r.Use(RecoveryWithWriter(&buf, func(c *Context, err interface{}) {
+		recovered = err
+		if !c.Writer.Written() {
+			c.JSON(http.StatusServiceUnavailable, H{"error": "try again"})
+		}
+	}))
+	r.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	r.GET("/written", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("late boom")
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || recovered != "boom" {
+		t.Fatalf("custom recovery should answer 503, got %d %v", w.Code, recovered)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret-token") || !strings.Contains(out, "Authorization: *") {
+		t.Fatalf("Authorization should be masked in %q", out)
+	}
+	if !strings.Contains(out, "boom") || !strings.Contains(out, "recovery_test.go") {
+		t.Fatalf("the panic and its trace should be logged, got %q", out)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/written", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("response already sent shouldn't be changed, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.EPIPE, syscall.ECONNRESET} {
+		var buf bytes.Buffer
+		r := New()
+		r.Use(RecoveryWithWriter(&buf))
+		r.GET("/", func(c *Context) {
+			panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", errno)})
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.Len() != 0 || !strings.Contains(buf.String(), "broken connection") {
+			t.Fatalf("%v: nothing should be written, got %q, log %q", errno, w.Body.String(), buf.String())
+		}
+	}
+}
+
+func TestRecoveryDefault(t *testing.T) {
+	r := New()
+	r.Use(RecoveryWithWriter(nil))
+	r.GET("/", func(c *Context) {
+		names := []string{"geektutu"}
+		c.String(http.StatusOK, names[100])
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "{\"message\":\"Internal Server Error\"}\n" {
+		t.Fatalf("expected 500, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,216 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const htmlContentType = "text/html"
+
+// HTML executes the template Name of Template with Data,
+// or Template itself when Name is empty
+type HTML struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+func (r HTML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Name == "" {
+		return r.Template.Execute(w, r.Data)
+	}
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+func (r HTML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
+
+// HTMLRender looks up the template name, it lets Context.HTML use
+// other template engines
+type HTMLRender interface {
+	Instance(name string, data interface{}) Render
+}
+
+// HTMLProduction renders the templates of a set parsed once
+type HTMLProduction struct {
+	Template *template.Template
+}
+
+func (r HTMLProduction) Instance(name string, data interface{}) Render {
+	return HTML{Template: r.Template, Name: name, Data: data}
+}
+
+// HTMLDebug parses the templates again on every render,
+// so that changes show up without restarting the server
+type HTMLDebug struct {
+	Load func() (*template.Template, error)
+}
+
+func (r HTMLDebug) Instance(name string, data interface{}) Render {
+	t, err := r.Load()
+	if err != nil {
+		return htmlError{err}
+	}
+	return HTML{Template: t, Name: name, Data: data}
+}
+
+// htmlError fails to render, for the templates which didn't parse
+type htmlError struct {
+	err error
+}
+
+func (r htmlError) Render(w http.ResponseWriter) error {
+	return r.err
+}
+
+func (r htmlError) WriteContentType(w http.ResponseWriter) {}
+
+// HTMLLayouts gives every page its own copy of the layouts and partials,
+// so that pages can redefine the same blocks. The instance name is the
+// path of a page file relative to the directory of its pattern, e.g.
+// "admin/user.html" for "pages/admin/user.html" matched by "pages/*/*.html".
+//
+//	layouts/base.html: <body>{{block "content" .}}{{end}}</body>
+//	pages/user.html:   {{define "content"}}{{.Name}}{{end}}
+//
+// With Layout set to "base.html", "user.html" renders the base layout
+// with the content of the user page.
+type HTMLLayouts struct {
+	// FS holds the files, they are read from the OS when nil
+	FS fs.FS
+	// Layouts are glob patterns of the layouts and partials shared by the pages
+	Layouts []string
+	// Pages are glob patterns of the pages
+	Pages []string
+	// Layout is the template executed for every page, the page itself when empty
+	Layout  string
+	FuncMap template.FuncMap
+	// Debug parses the files again on every render
+	Debug bool
+
+	mu    sync.Mutex
+	pages map[string]*template.Template
+}
+
+// Load parses the templates, Instance calls it when needed
+func (r *HTMLLayouts) Load() error {
+	pages, err := r.parse()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.pages = pages
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *HTMLLayouts) Instance(name string, data interface{}) Render {
+	pages := r.loaded()
+	if r.Debug || pages == nil {
+		var err error
+		if pages, err = r.parse(); err != nil {
+			return htmlError{err}
+		}
+		if !r.Debug {
+			r.mu.Lock()
+			r.pages = pages
+			r.mu.Unlock()
+		}
+	}
+	t, ok := pages[name]
+	if !ok {
+		return htmlError{&fs.PathError{Op: "render", Path: name, Err: fs.ErrNotExist}}
+	}
+	return HTML{Template: t, Name: r.entry(name), Data: data}
+}
+
+func (r *HTMLLayouts) loaded() map[string]*template.Template {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pages
+}
+
+func (r *HTMLLayouts) entry(name string) string {
+	if r.Layout != "" {
+		return r.Layout
+	}
+	return name
+}
+
+func (r *HTMLLayouts) parse() (map[string]*template.Template, error) {
+	layoutFiles, err := r.glob(r.Layouts)
+	if err != nil {
+		return nil, err
+	}
+	base := template.New("").Funcs(r.FuncMap)
+	if len(layoutFiles) > 0 {
+		if base, err = r.parseFiles(base, layoutFiles...); err != nil {
+			return nil, err
+		}
+	}
+	pages := make(map[string]*template.Template)
+	for _, pattern := range r.Pages {
+		files, err := r.glob([]string{pattern})
+		if err != nil {
+			return nil, err
+		}
+		root := globRoot(pattern)
+		for _, file := range files {
+			name := strings.TrimPrefix(filepath.ToSlash(file), root)
+			if _, ok := pages[name]; ok {
+				return nil, fmt.Errorf("render: pages %q share the name %q", file, name)
+			}
+			t, err := base.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if t, err = r.parseFiles(t, file); err != nil {
+				return nil, err
+			}
+			pages[name] = t
+		}
+	}
+	return pages, nil
+}
+
+// globRoot returns the directory of pattern before its first wildcard,
+// with a trailing slash unless empty
+func globRoot(pattern string) string {
+	pattern = filepath.ToSlash(pattern)
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	return pattern[:strings.LastIndexByte(pattern, '/')+1]
+}
+
+func (r *HTMLLayouts) glob(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		var matches []string
+		var err error
+		if r.FS != nil {
+			matches, err = fs.Glob(r.FS, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (r *HTMLLayouts) parseFiles(t *template.Template, files ...string) (*template.Template, error) {
+	if r.FS != nil {
+		return t.ParseFS(r.FS, files...)
+	}
+	return t.ParseFiles(files...)
+}
@@ -0,0 +1,173 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	jsonContentType       = "application/json"
+	jsonpContentType      = "application/javascript"
+	secureJSONDefaultHead = "while(1);"
+)
+
+// JSON encodes Data as JSON followed by a newline
+type JSON struct {
+	Data interface{}
+}
+
+func (r JSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+func (r JSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// IndentedJSON encodes Data as JSON indented by 4 spaces
+type IndentedJSON struct {
+	Data interface{}
+}
+
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// SecureJSON prefixes JSON arrays with Prefix, "while(1);" by default,
+// to prevent JSON hijacking
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(body, []byte("[")) && bytes.HasSuffix(body, []byte("]")) {
+		prefix := r.Prefix
+		if prefix == "" {
+			prefix = secureJSONDefaultHead
+		}
+		if _, err = w.Write([]byte(prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// AsciiJSON escapes every non-ASCII character as \uXXXX
+type AsciiJSON struct {
+	Data interface{}
+}
+
+func (r AsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for len(body) > 0 {
+		c, size := utf8.DecodeRune(body)
+		if c < utf8.RuneSelf {
+			buf.WriteByte(byte(c))
+		} else if c > 0xFFFF {
+			r1, r2 := utf16Surrogates(c)
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", r1, r2)
+		} else {
+			fmt.Fprintf(&buf, "\\u%04x", c)
+		}
+		body = body[size:]
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (r AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func utf16Surrogates(c rune) (rune, rune) {
+	c -= 0x10000
+	return 0xD800 + (c>>10)&0x3FF, 0xDC00 + c&0x3FF
+}
+
+// PureJSON leaves <, > and & unescaped, unlike JSON
+type PureJSON struct {
+	Data interface{}
+}
+
+func (r PureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+func (r PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// jsonpCallback matches the callbacks JSONP accepts, dotted identifiers
+// such as "jQuery.cb_1", so that a callback can't inject script
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)*$`)
+
+// JSONP wraps the JSON of Data in a call to Callback, it renders plain
+// JSON when Callback is empty or not a valid JavaScript identifier
+type JSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONP) Render(w http.ResponseWriter) error {
+	if !r.valid() {
+		return JSON{Data: r.Data}.Render(w)
+	}
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s(%s);", r.Callback, body)
+	return err
+}
+
+func (r JSONP) valid() bool {
+	return jsonpCallback.MatchString(r.Callback)
+}
+
+func (r JSONP) WriteContentType(w http.ResponseWriter) {
+	if !r.valid() {
+		writeContentType(w, jsonContentType)
+		return
+	}
+	writeContentType(w, jsonpContentType)
+}
@@ -0,0 +1,37 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const protobufContentType = "application/x-protobuf"
+
+// ProtoMarshal encodes the Data of ProtoBuf. By default it supports
+// messages with a Marshal() ([]byte, error) method, set it to use another
+// runtime, e.g. func(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) }
+var ProtoMarshal = func(v interface{}) ([]byte, error) {
+	if m, ok := v.(interface{ Marshal() ([]byte, error) }); ok {
+		return m.Marshal()
+	}
+	return nil, fmt.Errorf("render: %T is not a protobuf message, set render.ProtoMarshal", v)
+}
+
+// ProtoBuf encodes Data with ProtoMarshal
+type ProtoBuf struct {
+	Data interface{}
+}
+
+func (r ProtoBuf) Render(w http.ResponseWriter) error {
+	body, err := ProtoMarshal(r.Data)
+	if err != nil {
+		return err
+	}
+	r.WriteContentType(w)
+	_, err = w.Write(body)
+	return err
+}
+
+func (r ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, protobufContentType)
+}
@@ -0,0 +1,36 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Reader streams the content of Reader, ContentLength is ignored when negative
+type Reader struct {
+	ContentType   string
+	ContentLength int64
+	Reader        io.Reader
+	Headers       map[string]string
+}
+
+func (r Reader) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	header := w.Header()
+	if r.ContentLength >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	for key, value := range r.Headers {
+		if header.Get(key) == "" {
+			header.Set(key, value)
+		}
+	}
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+func (r Reader) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, r.ContentType)
+	}
+}
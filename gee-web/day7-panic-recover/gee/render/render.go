@@ -0,0 +1,37 @@
+// Package render implements the response renderers used by gee.Context
+package render
+
+import "net/http"
+
+// Render writes a response body of a given format
+type Render interface {
+	// Render writes the body, the status is sent by the caller
+	Render(http.ResponseWriter) error
+	// WriteContentType sets the Content-Type header if it isn't set yet
+	WriteContentType(w http.ResponseWriter)
+}
+
+var (
+	_ Render = JSON{}
+	_ Render = IndentedJSON{}
+	_ Render = SecureJSON{}
+	_ Render = AsciiJSON{}
+	_ Render = PureJSON{}
+	_ Render = JSONP{}
+	_ Render = XML{}
+	_ Render = YAML{}
+	_ Render = ProtoBuf{}
+	_ Render = String{}
+	_ Render = Data{}
+	_ Render = HTML{}
+	_ Render = Redirect{}
+	_ Render = Reader{}
+	_ Render = SSEvent{}
+)
+
+func writeContentType(w http.ResponseWriter, value string) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", value)
+	}
+}
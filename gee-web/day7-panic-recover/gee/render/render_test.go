@@ -0,0 +1,212 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestJSONRenders(t *testing.T) {
+	data := map[string]interface{}{"html": "<b>", "name": "极客兔兔"}
+	cases := []struct {
+		render Render
+		want   string
+		ctype  string
+	}{
+		{JSON{data}, "{\"html\":\"\\u003cb\\u003e\",\"name\":\"极客兔兔\"}\n", "application/json"},
+		{IndentedJSON{map[string]int{"a": 1}}, "{\n    \"a\": 1\n}", "application/json"},
+		{SecureJSON{Data: []int{1, 2}}, "while(1);[1,2]", "application/json"},
+		{SecureJSON{Data: map[string]int{"a": 1}}, "{\"a\":1}", "application/json"},
+		{AsciiJSON{map[string]string{"lang": "GO语言", "emoji": "😀"}}, "{\"emoji\":\"\\ud83d\\ude00\",\"lang\":\"GO\\u8bed\\u8a00\"}", "application/json"},
+		{PureJSON{data}, "{\"html\":\"<b>\",\"name\":\"极客兔兔\"}\n", "application/json"},
+		{JSONP{Callback: "cb", Data: []int{1}}, "cb([1]);", "application/javascript"},
+		{JSONP{Data: []int{1}}, "[1]\n", "application/json"},
+		{JSONP{Callback: "jQuery.cb_1$", Data: []int{1}}, "jQuery.cb_1$([1]);", "application/javascript"},
+		{JSONP{Callback: "alert(1);x", Data: []int{1}}, "[1]\n", "application/json"},
+		{JSONP{Callback: "cb</script>", Data: []int{1}}, "[1]\n", "application/json"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := tc.render.Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Body.String() != tc.want || w.Header().Get("Content-Type") != tc.ctype {
+			t.Fatalf("%T rendered %q as %s, want %q as %s", tc.render, w.Body.String(), w.Header().Get("Content-Type"), tc.want, tc.ctype)
+		}
+	}
+}
+
+func TestXML(t *testing.T) {
+	type item struct {
+		Name string `xml:"name"`
+	}
+	w := httptest.NewRecorder()
+	if err := (XML{item{"gee"}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "<item><name>gee</name></item>" || w.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("unexpected XML %q", w.Body.String())
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	type address struct {
+		City string `yaml:"city"`
+	}
+	type user struct {
+		Name      string            `yaml:"name"`
+		Age       int               `yaml:"age"`
+		Admin     bool              `yaml:"admin"`
+		Nickname  string            `yaml:"nickname,omitempty"`
+		Tags      []string          `yaml:"tags"`
+		Addresses []address         `yaml:"addresses"`
+		Meta      map[string]string `yaml:"meta"`
+		Empty     []int             `yaml:"empty"`
+		Version   string
+		Created   time.Time `yaml:"created"`
+	}
+	u := user{
+		Name:      "geek: tutu",
+		Age:       20,
+		Admin:     true,
+		Tags:      []string{"go", "yes"},
+		Addresses: []address{{"Beijing"}, {"Shanghai"}},
+		Meta:      map[string]string{"b": "2", "a": "x"},
+		Version:   "1.0",
+		Created:   time.Date(2020, 1, 9, 1, 0, 0, 0, time.UTC),
+	}
+	body, err := MarshalYAML(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name: "geek: tutu"
+age: 20
+admin: true
+tags:
+  - go
+  - "yes"
+addresses:
+  - city: Beijing
+  - city: Shanghai
+meta:
+  a: x
+  b: "2"
+empty: []
+version: "1.0"
+created: 2020-01-09T01:00:00Z
+`
+	if string(body) != want {
+		t.Fatalf("unexpected YAML:\n%s\nwant:\n%s", body, want)
+	}
+
+	body, _ = MarshalYAML([][]int{{1, 2}, {3}})
+	if string(body) != "- - 1\n  - 2\n- - 3\n" {
+		t.Fatalf("unexpected nested sequence %q", body)
+	}
+}
+
+func TestYAMLQuote(t *testing.T) {
+	quoted := []string{"", "~", "null", "NULL", "yes", "Off", "y", "12", "-1.5", "1e3", ".inf", "-.Inf", ".NaN",
+		"0x1F", "0o17", "0b101", "017", "1_000", "+1_000.5", "1:30", "190:20:30.15", "2024-01-02", "2001-12-14t21:59:43.10-05:00",
+		"<<", "=", "- item", "key: value", "a #comment", "trailing:"}
+	for _, s := range quoted {
+		if got := yamlQuote(s); got != strconv.Quote(s) {
+			t.Errorf("%q should be quoted, got %s", s, got)
+		}
+	}
+	for _, s := range []string{"gee", "v1.2", "a:b", "1-2", "0x", "C++", "hello world"} {
+		if got := yamlQuote(s); got != s {
+			t.Errorf("%q shouldn't be quoted, got %s", s, got)
+		}
+	}
+}
+
+type fakeMessage struct{ data string }
+
+func (m fakeMessage) Marshal() ([]byte, error) {
+	if m.data == "" {
+		return nil, errors.New("empty")
+	}
+	return []byte(m.data), nil
+}
+
+func TestProtoBuf(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (ProtoBuf{fakeMessage{"\x08\x01"}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "\x08\x01" || w.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("unexpected protobuf %q", w.Body.String())
+	}
+	if err := (ProtoBuf{struct{}{}}).Render(httptest.NewRecorder()); err == nil {
+		t.Fatal("non-message data should fail")
+	}
+}
+
+func TestSSEventAndReader(t *testing.T) {
+	w := httptest.NewRecorder()
+	SSEvent{Event: "message", ID: "1", Data: "line1\nline2"}.Render(w)
+	if w.Body.String() != "id:1\nevent:message\ndata:line1\ndata:line2\n\n" || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected event %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	Reader{ContentType: "text/csv", ContentLength: 3, Reader: strings.NewReader("a,b"), Headers: map[string]string{"X-Test": "1"}}.Render(w)
+	if w.Body.String() != "a,b" || w.Header().Get("Content-Length") != "3" || w.Header().Get("X-Test") != "1" {
+		t.Fatalf("unexpected reader response %q %v", w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/old", nil)
+	if err := (Redirect{Code: http.StatusOK, Request: req, Location: "/new"}).Render(w); err == nil {
+		t.Fatal("redirect with 200 should fail")
+	}
+	Redirect{Code: http.StatusFound, Request: req, Location: "/new"}.Render(w)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/new" {
+		t.Fatalf("unexpected redirect %d %v", w.Code, w.Header())
+	}
+}
+
+func TestHTMLLayouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":     {Data: []byte(`<title>{{block "title" .}}gee{{end}}</title>{{template "nav"}}{{block "content" .}}{{end}}`)},
+		"layouts/nav.html":      {Data: []byte(`{{define "nav"}}<nav>{{upper "home"}}</nav>{{end}}`)},
+		"pages/user.html":       {Data: []byte(`{{define "title"}}{{.}}{{end}}{{define "content"}}<p>user {{.}}</p>{{end}}`)},
+		"pages/articles.html":   {Data: []byte(`{{define "content"}}<ul></ul>{{end}}`)},
+		"pages/admin/user.html": {Data: []byte(`{{define "content"}}<p>admin {{.}}</p>{{end}}`)},
+	}
+	r := &HTMLLayouts{
+		FS:      fsys,
+		Layouts: []string{"layouts/*.html"},
+		Pages:   []string{"pages/*.html", "pages/*/*.html"},
+		Layout:  "base.html",
+		FuncMap: map[string]interface{}{"upper": strings.ToUpper},
+	}
+	cases := []struct{ page, want string }{
+		{"user.html", "<title>geektutu</title><nav>HOME</nav><p>user geektutu</p>"},
+		{"articles.html", "<title>gee</title><nav>HOME</nav><ul></ul>"},
+		{"admin/user.html", "<title>gee</title><nav>HOME</nav><p>admin geektutu</p>"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := r.Instance(tc.page, "geektutu").Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Body.String() != tc.want {
+			t.Fatalf("%s rendered %q, want %q", tc.page, w.Body.String(), tc.want)
+		}
+	}
+	if err := r.Instance("missing.html", nil).Render(httptest.NewRecorder()); err == nil {
+		t.Fatal("an unknown page should fail to render")
+	}
+
+	dup := &HTMLLayouts{FS: fsys, Pages: []string{"pages/*.html", "pages/admin/*.html"}}
+	if err := dup.Load(); err == nil {
+		t.Fatal("pages with the same name should fail to load")
+	}
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const sseContentType = "text/event-stream"
+
+// SSEvent is a Server-Sent Event, Data is written as is when it is a string
+// and encoded as JSON otherwise
+type SSEvent struct {
+	Event string
+	ID    string
+	Retry uint
+	Data  interface{}
+}
+
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return r.Encode(w)
+}
+
+// Encode writes the event in the text/event-stream format
+func (r SSEvent) Encode(w io.Writer) error {
+	var b strings.Builder
+	if r.ID != "" {
+		fmt.Fprintf(&b, "id:%s\n", sseEscape(r.ID))
+	}
+	if r.Event != "" {
+		fmt.Fprintf(&b, "event:%s\n", sseEscape(r.Event))
+	}
+	if r.Retry > 0 {
+		fmt.Fprintf(&b, "retry:%d\n", r.Retry)
+	}
+	data, ok := r.Data.(string)
+	if !ok {
+		body, err := json.Marshal(r.Data)
+		if err != nil {
+			return err
+		}
+		data = string(body)
+	}
+	for _, line := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		fmt.Fprintf(&b, "data:%s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	header.Set("Content-Type", sseContentType)
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+func sseEscape(s string) string {
+	return strings.NewReplacer("\n", "\\n", "\r", "\\r").Replace(s)
+}
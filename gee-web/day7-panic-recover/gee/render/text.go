@@ -0,0 +1,42 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const plainContentType = "text/plain"
+
+// String formats Format with Data, like fmt.Sprintf
+type String struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r String) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	_, err := fmt.Fprintf(w, r.Format, r.Data...)
+	return err
+}
+
+func (r String) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, plainContentType)
+}
+
+// Data writes raw bytes, ContentType is left out when empty
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+func (r Data) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	_, err := w.Write(r.Data)
+	return err
+}
+
+func (r Data) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, r.ContentType)
+	}
+}
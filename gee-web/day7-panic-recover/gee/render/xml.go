@@ -0,0 +1,22 @@
+package render
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+const xmlContentType = "application/xml"
+
+// XML encodes Data with encoding/xml
+type XML struct {
+	Data interface{}
+}
+
+func (r XML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return xml.NewEncoder(w).Encode(r.Data)
+}
+
+func (r XML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, xmlContentType)
+}
@@ -0,0 +1,298 @@
+package render
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const yamlContentType = "application/x-yaml"
+
+// YAML encodes Data as a block style YAML document. Struct fields are named
+// by their `yaml` tag, which supports omitempty and inline, or by their
+// lowercased name.
+type YAML struct {
+	Data interface{}
+}
+
+func (r YAML) Render(w http.ResponseWriter) error {
+	body, err := MarshalYAML(r.Data)
+	if err != nil {
+		return err
+	}
+	r.WriteContentType(w)
+	_, err = w.Write(body)
+	return err
+}
+
+func (r YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, yamlContentType)
+}
+
+// MarshalYAML returns the YAML encoding of v
+func MarshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	rv := reflect.ValueOf(v)
+	s, ok, err := yamlScalar(rv)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		buf.WriteString(s + "\n")
+		return buf.Bytes(), nil
+	}
+	if err := yamlBlock(&buf, rv, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// yamlScalar returns the inline form of v, ok is false when v must be
+// written as a block, i.e. a non-empty map, struct or sequence
+func yamlScalar(v reflect.Value) (s string, ok bool, err error) {
+	if !v.IsValid() {
+		return "null", true, nil
+	}
+	v = indirect(v)
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "null", true, nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return yamlQuote(string(text)), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsInf(f, 1):
+			return ".inf", true, nil
+		case math.IsInf(f, -1):
+			return "-.inf", true, nil
+		case math.IsNaN(f):
+			return ".nan", true, nil
+		}
+		return strconv.FormatFloat(f, 'g', -1, v.Type().Bits()), true, nil
+	case reflect.String:
+		return yamlQuote(v.String()), true, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return yamlQuote(string(v.Bytes())), true, nil
+		}
+		if v.Len() == 0 {
+			return "[]", true, nil
+		}
+		return "", false, nil
+	case reflect.Array:
+		if v.Len() == 0 {
+			return "[]", true, nil
+		}
+		return "", false, nil
+	case reflect.Map:
+		if v.Len() == 0 {
+			return "{}", true, nil
+		}
+		return "", false, nil
+	case reflect.Struct:
+		fields, err := yamlFields(v)
+		if err != nil {
+			return "", false, err
+		}
+		if len(fields) == 0 {
+			return "{}", true, nil
+		}
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("render: cannot encode %s as YAML", v.Type())
+}
+
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+func yamlFields(v reflect.Value) ([]yamlField, error) {
+	var fields []yamlField
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		tag := sf.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+		field := v.Field(i)
+		inline := strings.Contains(","+opts+",", ",inline,") || (sf.Anonymous && name == "")
+		if inline {
+			inner := indirect(field)
+			if inner.Kind() == reflect.Struct {
+				innerFields, err := yamlFields(inner)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, innerFields...)
+			}
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(field) {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		fields = append(fields, yamlField{key: name, value: field})
+	}
+	return fields, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return v.Type() == timeType && v.Interface().(time.Time).IsZero()
+	}
+	return v.IsZero()
+}
+
+// yamlBlock writes the map, struct or sequence v with every line indented by indent
+func yamlBlock(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	pad := strings.Repeat(" ", indent)
+
+	var entries []yamlField
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			s, ok, err := yamlScalar(item)
+			if err != nil {
+				return err
+			}
+			if ok {
+				buf.WriteString(pad + "- " + s + "\n")
+				continue
+			}
+			// write the nested block, then put "- " in place of its first indentation
+			var sub bytes.Buffer
+			if err := yamlBlock(&sub, item, indent+2); err != nil {
+				return err
+			}
+			buf.WriteString(pad + "- ")
+			buf.Write(sub.Bytes()[indent+2:])
+		}
+		return nil
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			s, ok, err := yamlScalar(key)
+			if err != nil || !ok {
+				return fmt.Errorf("render: cannot encode map key of type %s as YAML", key.Type())
+			}
+			entries = append(entries, yamlField{key: s, value: v.MapIndex(key)})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	case reflect.Struct:
+		fields, err := yamlFields(v)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			entries = append(entries, yamlField{key: yamlQuote(f.key), value: f.value})
+		}
+	default:
+		return fmt.Errorf("render: cannot encode %s as a YAML block", v.Type())
+	}
+
+	for _, entry := range entries {
+		s, ok, err := yamlScalar(entry.value)
+		if err != nil {
+			return err
+		}
+		if ok {
+			buf.WriteString(pad + entry.key + ": " + s + "\n")
+			continue
+		}
+		buf.WriteString(pad + entry.key + ":\n")
+		if err := yamlBlock(buf, entry.value, indent+2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlNonString matches the plain scalars which YAML 1.1 or 1.2 parsers
+// don't read as strings: ints in base 2, 8, 16 and 60 or with underscores,
+// floats, timestamps, null and the merge and value keys
+var yamlNonString = regexp.MustCompile(`^(?:` +
+	`[-+]?(?:0b[01_]+|0o[0-7_]+|0x[0-9a-fA-F_]+|[0-9][0-9_]*(?::[0-5]?[0-9])*(?:\.[0-9._]*)?(?:[eE][-+]?[0-9]+)?|\.[0-9][0-9._]*(?:[eE][-+]?[0-9]+)?|\.(?:inf|Inf|INF))` +
+	`|\.(?:nan|NaN|NAN)|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt \t].*)?|~|null|Null|NULL|<<|=` +
+	`)$`)
+
+// yamlQuote returns s, double quoted if it would be read back as another
+// type or break the document
+func yamlQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	if yamlNonString.MatchString(s) {
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@` \t") || strings.ContainsAny(s[len(s)-1:], " \t:") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return strconv.Quote(s)
+	}
+	for _, c := range s {
+		if c < ' ' || c == 0x7f || c == '\uFEFF' {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -2,75 +2,96 @@ package gee
 
 import (
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
 )
 
-type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+// Param is a URL param matched by a wildcard of the route
+type Param struct {
+	Key   string
+	Value string
 }
 
-func newRouter() *router {
-	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+// Params are the URL params of a route, in the order of the pattern
+type Params []Param
+
+// Get returns the value of the param name and whether it was matched
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
 	}
+	return "", false
 }
 
-// Only one * is allowed
-func parsePattern(pattern string) []string {
-	vs := strings.Split(pattern, "/")
+// ByName returns the value of the param name, or ""
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
 
-	parts := make([]string, 0)
-	for _, item := range vs {
-		if item != "" {
-			parts = append(parts, item)
-			if item[0] == '*' {
-				break
-			}
-		}
+type router struct {
+	maxParams   int // wildcards of the longest route, to size Context.Params
+	roots       map[string]*node
+	noRoute     []HandlerFunc // called when no route matches, 404 by default
+	noMethod    []HandlerFunc // called when only other methods match, 405 by default
+	allNoRoute  []HandlerFunc // engine middlewares followed by noRoute
+	allNoMethod []HandlerFunc // engine middlewares followed by noMethod
+}
+
+func newRouter() *router {
+	return &router{
+		roots: make(map[string]*node),
+		noRoute: []HandlerFunc{func(c *Context) {
+			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		}},
+		noMethod: []HandlerFunc{func(c *Context) {
+			c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Path)
+		}},
 	}
-	return parts
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
+// addRoute stores the complete handler chain of the route on its tree node,
+// it panics when pattern conflicts with a registered route
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc) {
+	if pattern == "" || pattern[0] != '/' {
+		panic("gee: pattern must begin with '/', got " + pattern)
+	}
 
-	key := method + "-" + pattern
 	_, ok := r.roots[method]
 	if !ok {
 		r.roots[method] = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	r.roots[method].insert(pattern, handlers)
+	if n := strings.Count(pattern, ":") + strings.Count(pattern, "*"); n > r.maxParams {
+		r.maxParams = n
+	}
 }
 
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+// match finds the route of path, filling params which is reset first
+func (r *router) match(method string, path string, params *Params) *node {
+	*params = (*params)[:0]
 	root, ok := r.roots[method]
-
 	if !ok {
-		return nil, nil
+		return nil
 	}
+	return root.search(path, params)
+}
 
-	n := root.search(searchParts, 0)
-
-	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
-		}
-		return n, params
+func (r *router) getRoute(method string, path string) (*node, map[string]string) {
+	var ps Params
+	n := r.match(method, path, &ps)
+	if n == nil {
+		return nil, nil
 	}
-
-	return nil, nil
+	params := make(map[string]string, len(ps))
+	for _, p := range ps {
+		params[p.Key] = p.Value
+	}
+	return n, params
 }
 
 func (r *router) getRoutes(method string) []*node {
@@ -83,17 +104,108 @@ func (r *router) getRoutes(method string) []*node {
 	return nodes
 }
 
-func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+// allowed returns the sorted methods, other than method, under which path is registered
+func (r *router) allowed(method string, path string) []string {
+	methods := make([]string, 0)
+	for m := range r.roots {
+		if m == method {
+			continue
+		}
+		if r.match(m, path, new(Params)) != nil {
+			methods = append(methods, m)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
 
-	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
+func (r *router) handle(c *Context) {
+	engine := c.engine
+	path, unescape := c.Path, false
+	if engine.UseRawPath && c.Req.URL.RawPath != "" {
+		path, unescape = c.Req.URL.RawPath, engine.UnescapePathValues
+	}
+	if n := r.match(c.Method, path, &c.Params); n != nil {
+		if unescape {
+			for i, p := range c.Params {
+				if v, err := url.PathUnescape(p.Value); err == nil {
+					c.Params[i].Value = v
+				}
+			}
+		}
+		c.handlers = n.handlers
+	} else if fixed, ok := r.fixPath(engine, c.Method, path); ok {
+		c.handlers = redirectHandlers(engine, fixed)
+	} else if allowed := r.allowed(c.Method, path); len(allowed) > 0 {
+		c.SetHeader("Allow", strings.Join(allowed, ", "))
+		c.handlers = r.allNoMethod
 	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		c.handlers = r.allNoRoute
 	}
 	c.Next()
 }
+
+// fixPath looks for the registered route path probably meant by the
+// request, according to the redirect options of engine
+func (r *router) fixPath(engine *Engine, method string, p string) (string, bool) {
+	root := r.roots[method]
+	if root == nil || method == "CONNECT" || p == "/" {
+		return "", false
+	}
+	if engine.RedirectTrailingSlash {
+		if alt := toggleTrailingSlash(p); alt != "" {
+			if r.match(method, alt, new(Params)) != nil {
+				return alt, true
+			}
+		}
+	}
+	if engine.RedirectFixedPath {
+		cleaned := cleanPath(p)
+		if fixed, ok := root.searchFold(cleaned); ok {
+			return fixed, true
+		}
+		if engine.RedirectTrailingSlash {
+			if alt := toggleTrailingSlash(cleaned); alt != "" {
+				if fixed, ok := root.searchFold(alt); ok {
+					return fixed, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func toggleTrailingSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return strings.TrimSuffix(p, "/")
+	}
+	return p + "/"
+}
+
+// cleanPath removes the . and .. elements and the repeated slashes of p,
+// keeping its trailing slash
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// redirectHandlers runs the engine middlewares then redirects to fixed,
+// with 301 for GET and HEAD and 308 to keep the method and body otherwise
+func redirectHandlers(engine *Engine, fixed string) []HandlerFunc {
+	handlers := make([]HandlerFunc, 0, len(engine.middlewares)+1)
+	handlers = append(handlers, engine.middlewares...)
+	return append(handlers, func(c *Context) {
+		code := http.StatusPermanentRedirect
+		if c.Method == "GET" || c.Method == "HEAD" {
+			code = http.StatusMovedPermanently
+		}
+		u := *c.Req.URL
+		// "//host" would be followed as another host by the client
+		u.Path, u.RawPath = "/"+strings.TrimLeft(fixed, "/"), ""
+		u.Scheme, u.Host, u.User = "", "", nil
+		c.Redirect(code, u.String())
+	})
+}
@@ -2,7 +2,9 @@ package gee
 
 import (
 	"fmt"
-	"reflect"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -16,15 +18,6 @@ func newTestRouter() *router {
 	return r
 }
 
-func TestParsePattern(t *testing.T) {
-	ok := reflect.DeepEqual(parsePattern("/p/:name"), []string{"p", ":name"})
-	ok = ok && reflect.DeepEqual(parsePattern("/p/*"), []string{"p", "*"})
-	ok = ok && reflect.DeepEqual(parsePattern("/p/*name/*"), []string{"p", "*name"})
-	if !ok {
-		t.Fatal("test parsePattern failed")
-	}
-}
-
 func TestGetRoute(t *testing.T) {
 	r := newTestRouter()
 	n, ps := r.getRoute("GET", "/hello/geektutu")
@@ -72,3 +65,76 @@ func TestGetRoutes(t *testing.T) {
 		t.Fatal("the number of routes shoule be 4")
 	}
 }
+
+func TestRedirectPath(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+	r.POST("/users/", func(c *Context) { c.String(http.StatusOK, "created") })
+	r.GET("/Users/:name/Profile", func(c *Context) { c.String(http.StatusOK, c.Param("name")) })
+
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{"GET", "/hello/", http.StatusMovedPermanently, "/hello"},
+		{"GET", "/hello/?lang=go", http.StatusMovedPermanently, "/hello?lang=go"},
+		{"POST", "/users", http.StatusPermanentRedirect, "/users/"},
+		{"GET", "/a/../hello", http.StatusMovedPermanently, "/hello"},
+		{"GET", "//hello", http.StatusMovedPermanently, "/hello"},
+		{"GET", "/HELLO", http.StatusMovedPermanently, "/hello"},
+		{"GET", "/users/GeekTutu/PROFILE/", http.StatusMovedPermanently, "/Users/GeekTutu/Profile"},
+		{"GET", "/hello", http.StatusOK, ""},
+		{"GET", "/nothing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Fatalf("%s %s: expected %d to %q, got %d to %q", tt.method, tt.path, tt.code, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	r.RedirectTrailingSlash, r.RedirectFixedPath = false, false
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/hello/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("redirects disabled, expected 404, got %d", w.Code)
+	}
+}
+
+func TestRedirectNoOpenRedirect(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.GET("/evil.com", func(c *Context) {})
+	for _, path := range []string{"//evil.com/", "///evil.com/"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusMovedPermanently && w.Code != http.StatusPermanentRedirect {
+			t.Fatalf("%s should be redirected, got %d", path, w.Code)
+		}
+		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/") || strings.HasPrefix(loc, "//") {
+			t.Fatalf("redirect of %s must stay on the host, got %q", path, loc)
+		}
+	}
+}
+
+func TestUseRawPath(t *testing.T) {
+	r := New()
+	r.UseRawPath = true
+	r.GET("/files/:name/meta", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/files/a%2Fb/meta", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "a/b" {
+		t.Fatalf("expected the unescaped param, got %d %q", w.Code, w.Body.String())
+	}
+
+	r.UnescapePathValues = false
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/files/a%2Fb/meta", nil))
+	if w.Body.String() != "a%2Fb" {
+		t.Fatalf("expected the raw param, got %q", w.Body.String())
+	}
+}
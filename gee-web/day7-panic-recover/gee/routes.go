@@ -0,0 +1,140 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Route is returned by the registration methods to name the route
+type Route struct {
+	engine  *Engine
+	methods []string
+	pattern string
+}
+
+// Name names the route for Engine.URL, it panics if name is already used
+// by another pattern
+func (route *Route) Name(name string) *Route {
+	engine := route.engine
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = make(map[string]string)
+		engine.routeNames = make(map[string]string)
+	}
+	if pattern, ok := engine.namedRoutes[name]; ok && pattern != route.pattern {
+		panic(fmt.Sprintf("gee: route name %q is already used by %q", name, pattern))
+	}
+	engine.namedRoutes[name] = route.pattern
+	for _, method := range route.methods {
+		engine.routeNames[method+" "+route.pattern] = name
+	}
+	return route
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Name        string      `json:"name,omitempty"`
+	Handler     string      `json:"handler"`
+	Middlewares []string    `json:"middlewares"`
+	HandlerFunc HandlerFunc `json:"-"`
+}
+
+// Routes returns the registered routes sorted by method, Handler is the
+// name of the last handler and Middlewares the names of the others
+func (engine *Engine) Routes() []RouteInfo {
+	methods := make([]string, 0, len(engine.router.roots))
+	for method := range engine.router.roots {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var routes []RouteInfo
+	for _, method := range methods {
+		for _, n := range engine.router.getRoutes(method) {
+			last := n.handlers[len(n.handlers)-1]
+			middlewares := make([]string, len(n.handlers)-1)
+			for i, h := range n.handlers[:len(n.handlers)-1] {
+				middlewares[i] = nameOfFunction(h)
+			}
+			routes = append(routes, RouteInfo{
+				Method:      method,
+				Path:        n.pattern,
+				Name:        engine.routeNames[method+" "+n.pattern],
+				Handler:     nameOfFunction(last),
+				Middlewares: middlewares,
+				HandlerFunc: last,
+			})
+		}
+	}
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// RoutesHandler answers with the route table as JSON, to register on
+// a debug path such as r.GET("/debug/routes", r.RoutesHandler())
+func (engine *Engine) RoutesHandler() HandlerFunc {
+	return func(c *Context) {
+		c.JSON(http.StatusOK, engine.Routes())
+	}
+}
+
+// URL builds the path of the route name, filling its wildcards with params.
+// Param values are escaped, the params which are not wildcards of the
+// pattern are added as the query string.
+func (engine *Engine) URL(name string, params map[string]string) (string, error) {
+	pattern, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gee: no route named %q", name)
+	}
+	used := make(map[string]bool)
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':':
+			end := i + 1
+			for end < len(pattern) && isParamByte(pattern[end]) {
+				end++
+			}
+			key := pattern[i+1 : end]
+			value, ok := params[key]
+			if !ok || value == "" {
+				return "", fmt.Errorf("gee: route %q requires param %q", name, key)
+			}
+			used[key] = true
+			b.WriteString(url.PathEscape(value))
+			i = end
+		case '*':
+			key := pattern[i+1:]
+			used[key] = true
+			segments := strings.Split(strings.TrimPrefix(params[key], "/"), "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+			i = len(pattern)
+		default:
+			b.WriteByte(pattern[i])
+			i++
+		}
+	}
+
+	query := url.Values{}
+	for key, value := range params {
+		if !used[key] {
+			query.Set(key, value)
+		}
+	}
+	if len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	return b.String(), nil
+}
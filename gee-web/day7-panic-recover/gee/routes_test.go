@@ -0,0 +1,88 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func showUser(c *Context) { c.String(http.StatusOK, c.Param("id")) }
+
+func authMiddleware(c *Context) { c.Next() }
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(Logger())
+	api := r.Group("/api")
+	api.Use(authMiddleware)
+	api.GET("/users/:id", showUser).Name("user.show")
+	api.POST("/users", func(c *Context) {})
+	r.GET("/debug/routes", r.RoutesHandler())
+
+	routes := r.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %d", len(routes))
+	}
+	var show RouteInfo
+	for _, route := range routes {
+		if route.Path == "/api/users/:id" {
+			show = route
+		}
+	}
+	if show.Method != "GET" || show.Name != "user.show" || !strings.HasSuffix(show.Handler, ".showUser") ||
+		len(show.Middlewares) != 2 || !strings.HasSuffix(show.Middlewares[1], ".authMiddleware") ||
+		!strings.Contains(show.Middlewares[0], "Logger") {
+		t.Fatalf("unexpected route %+v", show)
+	}
+	if routes[0].Method != "GET" || routes[len(routes)-1].Method != "POST" {
+		t.Fatalf("routes should be sorted by method, got %+v", routes)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+	var dumped []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &dumped); err != nil || len(dumped) != 3 {
+		t.Fatalf("unexpected route table %q", w.Body.String())
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", showUser).Name("user.show")
+	r.GET("/files/:name.:ext", showUser).Name("file")
+	r.GET("/static/*filepath", showUser).Name("static")
+	r.Any("/any", showUser).Name("any")
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"user.show", map[string]string{"id": "42"}, "/users/42"},
+		{"user.show", map[string]string{"id": "a b/c", "tab": "posts"}, "/users/a%20b%2Fc?tab=posts"},
+		{"file", map[string]string{"name": "report", "ext": "pdf"}, "/files/report.pdf"},
+		{"static", map[string]string{"filepath": "css/my site.css"}, "/static/css/my%20site.css"},
+		{"any", nil, "/any"},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.params)
+		if err != nil || got != tt.want {
+			t.Fatalf("URL(%q, %v) = %q, %v, want %q", tt.name, tt.params, got, err, tt.want)
+		}
+	}
+	if _, err := r.URL("user.show", nil); err == nil {
+		t.Fatal("a missing param should be an error")
+	}
+	if _, err := r.URL("unknown", nil); err == nil {
+		t.Fatal("an unknown name should be an error")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("reusing a name for another pattern should panic")
+		}
+	}()
+	r.POST("/users", showUser).Name("user.show")
+}
@@ -0,0 +1,172 @@
+package gee
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// newServer returns an http.Server for the engine, tracked for Shutdown
+func (engine *Engine) newServer(addr string) (*http.Server, error) {
+	// templates which don't parse fail the start of the server, not a request
+	if _, err := engine.htmlRender(); err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       engine.ReadTimeout,
+		ReadHeaderTimeout: engine.ReadHeaderTimeout,
+		WriteTimeout:      engine.WriteTimeout,
+		IdleTimeout:       engine.IdleTimeout,
+	}
+	if engine.UseH2C {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if engine.shuttingDown {
+		return nil, http.ErrServerClosed
+	}
+	engine.servers = append(engine.servers, srv)
+	return srv, nil
+}
+
+// serve ignores http.ErrServerClosed, which only means Shutdown was called
+func serve(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Run defines the method to start a http server,
+// it returns nil once Shutdown was called
+func (engine *Engine) Run(addr string) (err error) {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return serve(err)
+	}
+	return serve(srv.ListenAndServe())
+}
+
+// RunTLS starts a https server with the given certificate and key files,
+// HTTP/2 is negotiated automatically with the clients supporting it
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) (err error) {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return serve(err)
+	}
+	return serve(srv.ListenAndServeTLS(certFile, keyFile))
+}
+
+// RunUnix starts a http server on the unix socket file, replacing a stale one
+func (engine *Engine) RunUnix(file string) (err error) {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return engine.RunListener(listener)
+}
+
+// RunListener starts a http server accepting connections from listener
+func (engine *Engine) RunListener(listener net.Listener) (err error) {
+	srv, err := engine.newServer(listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		return serve(err)
+	}
+	return serve(srv.Serve(listener))
+}
+
+// OnShutdown registers fn to be called when Shutdown starts,
+// e.g. to close hijacked connections such as websockets
+func (engine *Engine) OnShutdown(fn func()) {
+	engine.mu.Lock()
+	engine.onShutdown = append(engine.onShutdown, fn)
+	engine.mu.Unlock()
+}
+
+// Shutdown stops the servers started by the Run methods from accepting
+// connections, calls the OnShutdown hooks, closes the websockets with
+// CloseGoingAway, then waits for the active handler chains to return,
+// or ctx to be done
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.mu.Lock()
+	engine.shuttingDown = true
+	servers := engine.servers
+	hooks := engine.onShutdown
+	websockets := make([]*WSConn, 0, len(engine.websockets))
+	for ws := range engine.websockets {
+		websockets = append(websockets, ws)
+	}
+	engine.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+	// hijacked connections are not closed by srv.Shutdown
+	for _, ws := range websockets {
+		ws.CloseWithCode(CloseGoingAway, "server shutting down")
+	}
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&engine.active) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// RunGraceful runs a http server on addr until one of signals, SIGINT and
+// SIGTERM by default, is received, then shuts down waiting at most timeout
+func (engine *Engine) RunGraceful(addr string, timeout time.Duration, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, signals...)
+	defer signal.Stop(quit)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- engine.Run(addr)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-quit:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		return err
+	}
+	return <-errs
+}
@@ -0,0 +1,100 @@
+package gee
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	var hooked bool
+	r.OnShutdown(func() { hooked = true })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- r.RunListener(listener) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{string(body), err}
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if res := <-results; res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request should complete, got %q %v", res.body, res.err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("RunListener should return nil after Shutdown, got %v", err)
+	}
+	if !hooked {
+		t.Fatal("OnShutdown hooks should be called")
+	}
+	if err := r.Run("127.0.0.1:0"); err != nil {
+		t.Fatalf("Run after Shutdown should return nil at once, got %v", err)
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gee.sock")
+	r := New()
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "unix") })
+	runErr := make(chan error, 1)
+	go func() { runErr <- r.RunUnix(file) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", file)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://gee/"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "unix" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	client.CloseIdleConnections()
+	r.Shutdown(context.Background())
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+}
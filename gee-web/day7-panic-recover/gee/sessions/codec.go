@@ -0,0 +1,140 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidValue is returned by Decode for forged, corrupted or expired values
+	ErrInvalidValue = errors.New("sessions: invalid cookie value")
+)
+
+type keyPair struct {
+	hashKey []byte
+	block   cipher.AEAD // nil when values are only signed
+}
+
+// Codec signs, and optionally encrypts, cookie values.
+// Values are encoded with the first key pair and decoded with any of them,
+// so keys are rotated by prepending the new pair and dropping the oldest later.
+type Codec struct {
+	pairs []keyPair
+	// MaxAge rejects values older than it when decoding, 0 disables the check
+	MaxAge time.Duration
+}
+
+// NewCodec returns a Codec for the given key pairs: a hash key, used for
+// HMAC-SHA256, followed by a block key of 16, 24 or 32 bytes for AES-GCM,
+// or nil to only sign. It panics on invalid keys.
+func NewCodec(keyPairs ...[]byte) *Codec {
+	if len(keyPairs) == 0 {
+		panic("sessions: at least one hash key is required")
+	}
+	codec := &Codec{MaxAge: 30 * 24 * time.Hour}
+	for i := 0; i < len(keyPairs); i += 2 {
+		pair := keyPair{hashKey: keyPairs[i]}
+		if len(pair.hashKey) == 0 {
+			panic("sessions: hash keys must not be empty")
+		}
+		if i+1 < len(keyPairs) && keyPairs[i+1] != nil {
+			block, err := aes.NewCipher(keyPairs[i+1])
+			if err != nil {
+				panic(fmt.Sprintf("sessions: invalid block key: %v", err))
+			}
+			if pair.block, err = cipher.NewGCM(block); err != nil {
+				panic(fmt.Sprintf("sessions: invalid block key: %v", err))
+			}
+		}
+		codec.pairs = append(codec.pairs, pair)
+	}
+	return codec
+}
+
+// Encode serializes value with encoding/gob and returns it signed,
+// and encrypted if the first key pair has a block key, for the cookie name
+func (c *Codec) Encode(name string, value interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return "", err
+	}
+	data := buf.Bytes()
+	pair := c.pairs[0]
+	if pair.block != nil {
+		nonce := make([]byte, pair.block.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		data = pair.block.Seal(nonce, nonce, data, []byte(name))
+	}
+	payload := strconv.FormatInt(time.Now().Unix(), 10) + "|" + base64.RawURLEncoding.EncodeToString(data)
+	mac := computeMAC(pair.hashKey, name, payload)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + base64.RawURLEncoding.EncodeToString(mac))), nil
+}
+
+// Decode verifies encoded with each key pair in turn and decodes it into dst
+func (c *Codec) Decode(name string, encoded string, dst interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidValue
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return ErrInvalidValue
+	}
+	payload := parts[0] + "|" + parts[1]
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidValue
+	}
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrInvalidValue
+	}
+	if c.MaxAge > 0 && time.Since(time.Unix(timestamp, 0)) > c.MaxAge {
+		return ErrInvalidValue
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidValue
+	}
+
+	for _, pair := range c.pairs {
+		if !hmac.Equal(mac, computeMAC(pair.hashKey, name, payload)) {
+			continue
+		}
+		plain := data
+		if pair.block != nil {
+			size := pair.block.NonceSize()
+			if len(data) < size {
+				return ErrInvalidValue
+			}
+			if plain, err = pair.block.Open(nil, data[:size], data[size:], []byte(name)); err != nil {
+				return ErrInvalidValue
+			}
+		}
+		if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(dst); err != nil {
+			return ErrInvalidValue
+		}
+		return nil
+	}
+	return ErrInvalidValue
+}
+
+func computeMAC(key []byte, name string, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name + "|" + payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,207 @@
+// Package sessions provides cookie based sessions for gee,
+// with values kept in the cookie, in memory or in files
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"gee"
+)
+
+// DefaultKey is the gee.Context key of the session set by Sessions
+const DefaultKey = "gee/sessions"
+
+const flashesKey = "_flash"
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Options are the attributes of the session cookie
+type Options struct {
+	Path     string
+	Domain   string
+	MaxAge   int // seconds, < 0 deletes the session
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// DefaultOptions are used by the stores created by this package
+var DefaultOptions = Options{Path: "/", MaxAge: 30 * 86400, HttpOnly: true, SameSite: http.SameSiteLaxMode}
+
+// State is the session data exchanged with a Store
+type State struct {
+	ID      string // empty for sessions stored in the cookie itself
+	Name    string
+	Values  map[string]interface{}
+	Options Options
+	IsNew   bool
+}
+
+// Store loads and saves sessions. Values must be registered with gob.Register
+// if they aren't basic types.
+type Store interface {
+	// Get returns the session name of r, a new one when it is missing or invalid
+	Get(r *http.Request, name string) (*State, error)
+	// Save persists state and sets its cookie on w, or deletes it if MaxAge < 0
+	Save(r *http.Request, w http.ResponseWriter, state *State) error
+}
+
+// Session is the session of a request, see Default
+type Session interface {
+	// ID is the identifier of server-side sessions, empty otherwise
+	ID() string
+	Get(key string) interface{}
+	Set(key string, value interface{})
+	Delete(key string)
+	// Clear removes all the values
+	Clear()
+	// AddFlash adds a message read once by Flashes, under vars[0] if given
+	AddFlash(value interface{}, vars ...string)
+	// Flashes returns and removes the flash messages
+	Flashes(vars ...string) []interface{}
+	// Options changes the cookie attributes, MaxAge < 0 deletes the session on Save
+	Options(Options)
+	// Save persists the session, it must be called before the body is written
+	Save() error
+}
+
+type session struct {
+	name    string
+	store   Store
+	c       *gee.Context
+	state   *State
+	written bool
+}
+
+// Sessions loads the session name from store for each request,
+// handlers get it with Default
+func Sessions(name string, store Store) gee.HandlerFunc {
+	return func(c *gee.Context) {
+		c.Set(DefaultKey, &session{name: name, store: store, c: c})
+		c.Next()
+	}
+}
+
+// Default returns the session set by the Sessions middleware
+func Default(c *gee.Context) Session {
+	return c.MustGet(DefaultKey).(Session)
+}
+
+// load reads the session from the store on first use
+func (s *session) load() *State {
+	if s.state == nil {
+		state, err := s.store.Get(s.c.Req, s.name)
+		if err != nil {
+			log.Printf("[sessions] %s: %v", s.name, err)
+		}
+		s.state = state
+	}
+	return s.state
+}
+
+func (s *session) ID() string {
+	return s.load().ID
+}
+
+func (s *session) Get(key string) interface{} {
+	return s.load().Values[key]
+}
+
+func (s *session) Set(key string, value interface{}) {
+	s.load().Values[key] = value
+	s.written = true
+}
+
+func (s *session) Delete(key string) {
+	delete(s.load().Values, key)
+	s.written = true
+}
+
+func (s *session) Clear() {
+	state := s.load()
+	for key := range state.Values {
+		delete(state.Values, key)
+	}
+	s.written = true
+}
+
+func (s *session) AddFlash(value interface{}, vars ...string) {
+	key := flashesKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	state := s.load()
+	flashes, _ := state.Values[key].([]interface{})
+	state.Values[key] = append(flashes, value)
+	s.written = true
+}
+
+func (s *session) Flashes(vars ...string) []interface{} {
+	key := flashesKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	state := s.load()
+	flashes, _ := state.Values[key].([]interface{})
+	if flashes != nil {
+		delete(state.Values, key)
+		s.written = true
+	}
+	return flashes
+}
+
+func (s *session) Options(options Options) {
+	s.load().Options = options
+	s.written = true
+}
+
+func (s *session) Save() error {
+	if !s.written {
+		return nil
+	}
+	if err := s.store.Save(s.c.Req, s.c.Writer, s.load()); err != nil {
+		return err
+	}
+	s.written = false
+	return nil
+}
+
+func newState(name string) *State {
+	return &State{Name: name, Values: make(map[string]interface{}), Options: DefaultOptions, IsNew: true}
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func isValidID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func newCookie(name string, value string, options Options) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+		SameSite: options.SameSite,
+	}
+}
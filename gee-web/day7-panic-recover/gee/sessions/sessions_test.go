@@ -0,0 +1,159 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gee"
+)
+
+var (
+	hashKey  = []byte("0123456789abcdef0123456789abcdef")
+	blockKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestCodecRotation(t *testing.T) {
+	old := NewCodec([]byte("old-hash-key"), nil)
+	encoded, err := old.Encode("session", map[string]interface{}{"user": "geektutu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := NewCodec(hashKey, blockKey, []byte("old-hash-key"), nil)
+	var values map[string]interface{}
+	if err := rotated.Decode("session", encoded, &values); err != nil || values["user"] != "geektutu" {
+		t.Fatalf("values signed with an old key should decode, got %v %v", values, err)
+	}
+
+	encrypted, _ := rotated.Encode("session", "secret")
+	if strings.Contains(encrypted, "secret") {
+		t.Fatal("the value should be encrypted")
+	}
+	var s string
+	if err := rotated.Decode("session", encrypted, &s); err != nil || s != "secret" {
+		t.Fatalf("expected secret, got %q %v", s, err)
+	}
+	if err := rotated.Decode("other", encrypted, &s); err != ErrInvalidValue {
+		t.Fatal("values are bound to the cookie name")
+	}
+	if err := old.Decode("session", encrypted, &s); err != ErrInvalidValue {
+		t.Fatal("unknown keys should be rejected")
+	}
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if err := rotated.Decode("session", tampered, &s); err != ErrInvalidValue {
+		t.Fatal("tampered values should be rejected")
+	}
+}
+
+func newSessionEngine(store Store) *gee.Engine {
+	r := gee.New()
+	r.Use(Sessions("geesession", store))
+	r.GET("/login", func(c *gee.Context) {
+		session := Default(c)
+		session.Set("user", "geektutu")
+		session.Set("visits", 1)
+		session.AddFlash("welcome")
+		if err := session.Save(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/me", func(c *gee.Context) {
+		session := Default(c)
+		flashes := session.Flashes()
+		session.Save()
+		c.JSON(http.StatusOK, gee.H{"user": session.Get("user"), "visits": session.Get("visits"), "flashes": flashes})
+	})
+	r.GET("/logout", func(c *gee.Context) {
+		session := Default(c)
+		session.Clear()
+		session.Options(Options{Path: "/", MaxAge: -1})
+		session.Save()
+		c.String(http.StatusOK, "bye")
+	})
+	return r
+}
+
+func do(r *gee.Engine, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"cookie": NewCookieStore(hashKey, blockKey),
+		"memory": NewMemoryStore(hashKey),
+		"file":   NewFileStore(t.TempDir(), hashKey),
+	}
+	for name, store := range stores {
+		r := newSessionEngine(store)
+		w := do(r, "/login", nil)
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusOK || len(cookies) != 1 || !cookies[0].HttpOnly {
+			t.Fatalf("%s: login should set the session cookie, got %d %v", name, w.Code, cookies)
+		}
+
+		w = do(r, "/me", cookies)
+		if body := w.Body.String(); body != "{\"flashes\":[\"welcome\"],\"user\":\"geektutu\",\"visits\":1}\n" {
+			t.Fatalf("%s: unexpected session %s", name, body)
+		}
+		if name != "cookie" {
+			// the flash was consumed on the server side
+			w = do(r, "/me", cookies)
+			if body := w.Body.String(); body != "{\"flashes\":null,\"user\":\"geektutu\",\"visits\":1}\n" {
+				t.Fatalf("%s: flash should be read once, got %s", name, body)
+			}
+		}
+
+		w = do(r, "/logout", cookies)
+		if deleted := w.Result().Cookies(); len(deleted) != 1 || deleted[0].MaxAge >= 0 {
+			t.Fatalf("%s: logout should delete the cookie, got %v", name, deleted)
+		}
+		if name != "cookie" {
+			w = do(r, "/me", cookies)
+			if body := w.Body.String(); body != "{\"flashes\":null,\"user\":null,\"visits\":null}\n" {
+				t.Fatalf("%s: session should be gone, got %s", name, body)
+			}
+		}
+
+		forged := []*http.Cookie{{Name: "geesession", Value: "forged"}}
+		w = do(r, "/me", forged)
+		if body := w.Body.String(); body != "{\"flashes\":null,\"user\":null,\"visits\":null}\n" {
+			t.Fatalf("%s: forged cookie should give an empty session, got %s", name, body)
+		}
+	}
+}
+
+func TestContextCookie(t *testing.T) {
+	r := gee.New()
+	r.GET("/", func(c *gee.Context) {
+		value, err := c.Cookie("lang")
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie("seen", "yes & no", 3600, "", "", true, true)
+		if err != nil {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, value)
+	})
+	w := do(r, "/", []*http.Cookie{{Name: "lang", Value: "zh%20CN"}})
+	cookies := w.Result().Cookies()
+	if w.Body.String() != "zh CN" || len(cookies) != 1 {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), cookies)
+	}
+	cookie := cookies[0]
+	if cookie.Value != "yes+%26+no" || cookie.Path != "/" || cookie.MaxAge != 3600 || !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("unexpected cookie %+v", cookie)
+	}
+	if w = do(r, "/", nil); w.Body.String() != "none" {
+		t.Fatalf("missing cookie should fail, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,253 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CookieStore keeps the session values in the cookie itself,
+// signed and optionally encrypted by its Codec
+type CookieStore struct {
+	Codec *Codec
+}
+
+// NewCookieStore returns a CookieStore, see NewCodec for the key pairs
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	return &CookieStore{Codec: NewCodec(keyPairs...)}
+}
+
+func (s *CookieStore) Get(r *http.Request, name string) (*State, error) {
+	state := newState(name)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return state, nil
+	}
+	values := make(map[string]interface{})
+	if err := s.Codec.Decode(name, cookie.Value, &values); err != nil {
+		return state, err
+	}
+	state.Values = values
+	state.IsNew = false
+	return state, nil
+}
+
+func (s *CookieStore) Save(r *http.Request, w http.ResponseWriter, state *State) error {
+	if state.Options.MaxAge < 0 {
+		http.SetCookie(w, newCookie(state.Name, "", state.Options))
+		return nil
+	}
+	encoded, err := s.Codec.Encode(state.Name, state.Values)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, newCookie(state.Name, encoded, state.Options))
+	return nil
+}
+
+// serverStore holds what MemoryStore and FileStore share: the cookie only
+// carries the signed session ID
+type serverStore struct {
+	Codec *Codec
+}
+
+// loadID returns the session ID in the cookie of r, or "" with a new state
+func (s *serverStore) loadID(r *http.Request, name string) (string, *State, error) {
+	state := newState(name)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", state, nil
+	}
+	var id string
+	if err := s.Codec.Decode(name, cookie.Value, &id); err != nil || !isValidID(id) {
+		return "", state, ErrInvalidValue
+	}
+	return id, state, nil
+}
+
+func (s *serverStore) saveCookie(w http.ResponseWriter, state *State) error {
+	if state.Options.MaxAge < 0 {
+		http.SetCookie(w, newCookie(state.Name, "", state.Options))
+		return nil
+	}
+	encoded, err := s.Codec.Encode(state.Name, state.ID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, newCookie(state.Name, encoded, state.Options))
+	return nil
+}
+
+// lifetime is how long a server-side session is kept, a day for session cookies
+func lifetime(options Options) time.Duration {
+	if options.MaxAge == 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(options.MaxAge) * time.Second
+}
+
+type memoryEntry struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// MemoryStore keeps the sessions in the memory of the process,
+// expired sessions are evicted while saving
+type MemoryStore struct {
+	serverStore
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a MemoryStore, see NewCodec for the key pairs
+func NewMemoryStore(keyPairs ...[]byte) *MemoryStore {
+	return &MemoryStore{
+		serverStore: serverStore{Codec: NewCodec(keyPairs...)},
+		sessions:    make(map[string]memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Get(r *http.Request, name string) (*State, error) {
+	id, state, err := s.loadID(r, name)
+	if id == "" {
+		return state, err
+	}
+	s.mu.Lock()
+	entry, ok := s.sessions[id]
+	if ok && time.Now().After(entry.expires) {
+		delete(s.sessions, id)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		return state, nil
+	}
+	// copy so that concurrent requests of a session don't share the map
+	for key, value := range entry.values {
+		state.Values[key] = value
+	}
+	state.ID = id
+	state.IsNew = false
+	return state, nil
+}
+
+func (s *MemoryStore) Save(r *http.Request, w http.ResponseWriter, state *State) error {
+	now := time.Now()
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for id, entry := range s.sessions {
+			if now.After(entry.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	if state.Options.MaxAge < 0 {
+		delete(s.sessions, state.ID)
+	} else {
+		if state.ID == "" {
+			state.ID = newID()
+		}
+		values := make(map[string]interface{}, len(state.Values))
+		for key, value := range state.Values {
+			values[key] = value
+		}
+		s.sessions[state.ID] = memoryEntry{values, now.Add(lifetime(state.Options))}
+	}
+	s.mu.Unlock()
+	return s.saveCookie(w, state)
+}
+
+// FileStore keeps each session in a file of Dir, encoded with encoding/gob
+type FileStore struct {
+	serverStore
+	Dir string
+	mu  sync.RWMutex
+}
+
+// NewFileStore returns a FileStore writing in dir, the temporary directory
+// if it is empty, see NewCodec for the key pairs
+func NewFileStore(dir string, keyPairs ...[]byte) *FileStore {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &FileStore{serverStore: serverStore{Codec: NewCodec(keyPairs...)}, Dir: dir}
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.Dir, "session_"+id)
+}
+
+func (s *FileStore) Get(r *http.Request, name string) (*State, error) {
+	id, state, err := s.loadID(r, name)
+	if id == "" {
+		return state, err
+	}
+	s.mu.RLock()
+	data, err := os.ReadFile(s.path(id))
+	s.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	var entry struct {
+		Values  map[string]interface{}
+		Expires time.Time
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return state, err
+	}
+	if time.Now().After(entry.Expires) {
+		s.mu.Lock()
+		os.Remove(s.path(id))
+		s.mu.Unlock()
+		return state, nil
+	}
+	if entry.Values != nil {
+		state.Values = entry.Values
+	}
+	state.ID = id
+	state.IsNew = false
+	return state, nil
+}
+
+func (s *FileStore) Save(r *http.Request, w http.ResponseWriter, state *State) error {
+	if state.Options.MaxAge < 0 {
+		if state.ID != "" {
+			s.mu.Lock()
+			err := os.Remove(s.path(state.ID))
+			s.mu.Unlock()
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		return s.saveCookie(w, state)
+	}
+
+	if state.ID == "" {
+		state.ID = newID()
+	}
+	var buf bytes.Buffer
+	entry := struct {
+		Values  map[string]interface{}
+		Expires time.Time
+	}{state.Values, time.Now().Add(lifetime(state.Options))}
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	err := os.WriteFile(s.path(state.ID), buf.Bytes(), 0600)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.saveCookie(w, state)
+}
@@ -0,0 +1,224 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// StaticConfig configures StaticFS
+type StaticConfig struct {
+	// Browse lists the directories without an index file
+	Browse bool
+	// Index is the file served for a directory, "index.html" by default
+	Index string
+	// CacheControl is the Cache-Control header of the files, unset if empty
+	CacheControl string
+	// Precompressed serves name.gz, when it exists, to clients accepting gzip
+	Precompressed bool
+	// Fallback is the file served for unknown paths, such as "index.html"
+	// for a single page application, 404 is answered if empty
+	Fallback string
+}
+
+// serve static files
+func (group *RouterGroup) Static(relativePath string, root string) {
+	group.StaticFS(relativePath, os.DirFS(root))
+}
+
+// StaticFS serves the files of fsys, such as an embed.FS, under relativePath
+// for GET and HEAD requests. Files get an ETag and Last-Modified, and
+// conditional and range requests are answered as by http.ServeContent.
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS, config ...StaticConfig) {
+	var cfg StaticConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Index == "" {
+		cfg.Index = "index.html"
+	}
+	s := &staticServer{fsys: fsys, config: cfg}
+	handler := func(c *Context) {
+		s.serve(c, c.Param("filepath"))
+	}
+	urlPattern := path.Join(relativePath, "/*filepath")
+	group.GET(urlPattern, handler)
+	group.HEAD(urlPattern, handler)
+}
+
+// StaticFile serves the file at filepath on relativePath for GET and HEAD requests
+func (group *RouterGroup) StaticFile(relativePath string, filepath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("gee: URL parameters can not be used when serving a static file")
+	}
+	dir, name := path.Split(filepath)
+	if dir == "" {
+		dir = "."
+	}
+	s := &staticServer{fsys: os.DirFS(dir)}
+	handler := func(c *Context) {
+		s.serveFile(c, name)
+	}
+	group.GET(relativePath, handler)
+	group.HEAD(relativePath, handler)
+}
+
+type staticServer struct {
+	fsys   fs.FS
+	config StaticConfig
+	hashes sync.Map // name -> ETag of the files without modification time
+}
+
+func (s *staticServer) serve(c *Context, filepath string) {
+	name := strings.TrimPrefix(path.Clean("/"+filepath), "/")
+	if name == "" {
+		name = "."
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() {
+		// relative links of the index page need the trailing slash
+		if !strings.HasSuffix(c.Req.URL.Path, "/") {
+			u := *c.Req.URL
+			// "//host/" would be followed as another host by the client
+			u.Path, u.RawPath = "/"+strings.TrimLeft(u.Path, "/")+"/", ""
+			c.Redirect(http.StatusMovedPermanently, u.String())
+			return
+		}
+		index := path.Join(name, s.config.Index)
+		if indexInfo, err := fs.Stat(s.fsys, index); err == nil && !indexInfo.IsDir() {
+			name = index
+		} else if s.config.Browse {
+			s.list(c, name)
+			return
+		} else {
+			err = fs.ErrNotExist
+		}
+	}
+	if err != nil {
+		if s.config.Fallback == "" {
+			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+			return
+		}
+		name = s.config.Fallback
+	}
+	s.serveFile(c, name)
+}
+
+func (s *staticServer) serveFile(c *Context, name string) {
+	header := c.Writer.Header()
+	if s.config.CacheControl != "" {
+		header.Set("Cache-Control", s.config.CacheControl)
+	}
+	if s.config.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		gzipOnly := []Compressor{{Encoding: "gzip"}}
+		if negotiateEncoding(c.Req.Header.Get("Accept-Encoding"), gzipOnly) == 0 {
+			if f, info, err := s.open(name + ".gz"); err == nil {
+				defer f.Close()
+				contentType := mime.TypeByExtension(path.Ext(name))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				header.Set("Content-Type", contentType)
+				header.Set("Content-Encoding", "gzip")
+				s.serveContent(c, name+".gz", f, info)
+				return
+			}
+		}
+	}
+
+	f, info, err := s.open(name)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		return
+	}
+	defer f.Close()
+	s.serveContent(c, name, f, info)
+}
+
+// open opens the regular file name
+func (s *staticServer) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+func (s *staticServer) serveContent(c *Context, name string, f fs.File, info fs.FileInfo) {
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	if etag, err := s.etag(name, content, info); err == nil {
+		c.Writer.Header().Set("ETag", etag)
+	}
+	http.ServeContent(c.Writer, c.Req, info.Name(), info.ModTime(), content)
+}
+
+// etag derives the ETag from the size and modification time of the file,
+// or from its content when it has no modification time, as in an embed.FS
+func (s *staticServer) etag(name string, content io.ReadSeeker, info fs.FileInfo) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	if etag, ok := s.hashes.Load(name); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.hashes.Store(name, etag)
+	return etag, nil
+}
+
+// list writes the entries of the directory name as links
+func (s *staticServer) list(c *Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Data(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,113 @@
+package gee
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func staticRequest(r *Engine, method, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticFS(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("console.log('gee')"))
+	zw.Close()
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("<h1>app</h1>")},
+		"js/app.js":      {Data: []byte("console.log('gee')")},
+		"js/app.js.gz":   {Data: gz.Bytes()},
+		"docs/readme.md": {Data: []byte("# readme")},
+	}
+	r := New()
+	r.StaticFS("/app", fsys, StaticConfig{Precompressed: true, CacheControl: "public, max-age=60", Fallback: "index.html"})
+	r.StaticFS("/files", fsys, StaticConfig{Browse: true})
+	r.StaticFS("/plain", fsys)
+
+	w := staticRequest(r, "GET", "/app/js/app.js")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('gee')" || etag == "" ||
+		w.Header().Get("Cache-Control") != "public, max-age=60" || !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("unexpected response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+	if w := staticRequest(r, "GET", "/app/js/app.js", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+	if w := staticRequest(r, "HEAD", "/app/js/app.js"); w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "18" {
+		t.Fatalf("unexpected HEAD response %d %v", w.Code, w.Header())
+	}
+
+	w = staticRequest(r, "GET", "/app/js/app.js", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || !bytes.Equal(w.Body.Bytes(), gz.Bytes()) ||
+		!strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("expected the precompressed variant, got %v", w.Header())
+	}
+
+	if w := staticRequest(r, "GET", "/app/users/42"); w.Code != http.StatusOK || w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("unknown paths should fall back to index.html, got %d %q", w.Code, w.Body.String())
+	}
+	if w := staticRequest(r, "GET", "/app/"); w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("directory should serve its index, got %q", w.Body.String())
+	}
+
+	if w := staticRequest(r, "GET", "/files/docs"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/files/docs/" {
+		t.Fatalf("expected a redirect to the directory, got %d %v", w.Code, w.Header())
+	}
+	if w := staticRequest(r, "GET", "/files/docs/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<a href="readme.md">readme.md</a>`) {
+		t.Fatalf("expected a directory listing, got %d %q", w.Code, w.Body.String())
+	}
+	if w := staticRequest(r, "GET", "/plain/docs/"); w.Code != http.StatusNotFound {
+		t.Fatalf("listing should be disabled by default, got %d", w.Code)
+	}
+	if w := staticRequest(r, "GET", "/plain/../index.html"); w.Code != http.StatusOK {
+		t.Fatalf("cleaned path should be served, got %d", w.Code)
+	}
+	if w := staticRequest(r, "GET", "/plain/missing.txt"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestStaticNoOpenRedirect(t *testing.T) {
+	r := New()
+	r.StaticFS("/", fstest.MapFS{"evil.com/index.html": {Data: []byte("<h1>evil</h1>")}})
+	for _, path := range []string{"//evil.com", "///evil.com"} {
+		w := staticRequest(r, "GET", path)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/evil.com/" {
+			t.Fatalf("%s should be redirected on the host, got %d %q", path, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestStaticDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "favicon.ico"), []byte("icon"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Static("/assets", dir)
+	r.StaticFile("/favicon.ico", filepath.Join(dir, "favicon.ico"))
+
+	for _, path := range []string{"/assets/favicon.ico", "/favicon.ico"} {
+		w := staticRequest(r, "GET", path)
+		if w.Code != http.StatusOK || w.Body.String() != "icon" || w.Header().Get("Last-Modified") == "" || w.Header().Get("ETag") == "" {
+			t.Fatalf("%s: unexpected response %d %v", path, w.Code, w.Header())
+		}
+		if w := staticRequest(r, "GET", path, "If-Modified-Since", w.Header().Get("Last-Modified")); w.Code != http.StatusNotModified {
+			t.Fatalf("%s: expected 304, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package gee
+
+import "net/http"
+
+// CreateTestContext returns a Context writing to w, and its new Engine,
+// to unit test a HandlerFunc without routing. The request is GET / unless
+// one is given. Call c.Writer.WriteHeaderNow() after the handler when it
+// may only set the status.
+func CreateTestContext(w http.ResponseWriter, req ...*http.Request) (*Context, *Engine) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	if len(req) > 0 && req[0] != nil {
+		r = req[0]
+	}
+	engine := New()
+	c := newContext(w, r)
+	c.engine = engine
+	return c, engine
+}
@@ -0,0 +1,165 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout runs the pending handlers with a deadline of d. They run in
+// their own goroutine against a buffered response: if they return in time
+// the response is copied to the client, otherwise 503 is written and their
+// late writes are dropped. Handlers should still watch c.Done() to stop
+// working once the deadline is over, and must not stream the response.
+func Timeout(d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK, size: noWritten}
+		inner := c.Copy()
+		inner.Req = c.Req.WithContext(ctx)
+		inner.Writer = tw
+		inner.handlers = c.handlers
+		inner.index = c.index
+		c.Abort() // the rest of the chain is run by inner
+
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			inner.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			inner.mu.RLock()
+			for key, value := range inner.Keys {
+				c.Set(key, value)
+			}
+			inner.mu.RUnlock()
+			tw.copyTo(c.Writer)
+		case <-ctx.Done():
+			tw.timeout()
+			c.abortWithContextErr(ctx.Err())
+		}
+	}
+}
+
+// timeoutWriter buffers the response of the handlers run by Timeout
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	size     int
+	timedOut bool
+}
+
+var _ ResponseWriter = &timeoutWriter{}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && w.size == noWritten {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size == noWritten {
+		w.size = 0
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.body.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+// Written is true once timed out, the response then belongs to Timeout
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.timedOut || w.size != noWritten
+}
+
+// Flush does nothing, the response is only sent once the handlers returned
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("gee: the connection can't be hijacked under Timeout")
+}
+
+func (w *timeoutWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// timeout drops the writes made from now on
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	w.timedOut = true
+	w.mu.Unlock()
+}
+
+// copyTo sends the buffered response to dst
+func (w *timeoutWriter) copyTo(dst ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	header := dst.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	dst.WriteHeader(w.status)
+	if w.size != noWritten {
+		dst.WriteHeaderNow()
+		dst.Write(w.body.Bytes())
+	}
+}
@@ -5,77 +5,286 @@ import (
 	"strings"
 )
 
+type nodeType uint8
+
+const (
+	static   nodeType = iota // compressed static text
+	param                    // :name, matches up to the next '/' or static text
+	catchAll                 // *name, matches the rest of the path
+)
+
+// node is a node of the compressed radix tree used by router.
+// When matching, static children win over the param child,
+// which wins over the catch-all child.
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWild   bool
+	pattern       string // full pattern of the route ending here, empty otherwise
+	path          string // static text, ":name" or "*name"
+	typ           nodeType
+	indices       string  // first byte of every static child
+	children      []*node // static children, in the order of indices
+	paramChild    *node
+	catchAllChild *node
+	handlers      []HandlerFunc // group middlewares followed by the route handlers
 }
 
 func (n *node) String() string {
-	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
+	return fmt.Sprintf("node{pattern=%s, path=%s, isWild=%t}", n.pattern, n.path, n.typ != static)
+}
+
+func isParamByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// insert adds pattern below n, it panics if pattern is malformed
+// or conflicts with a route registered before
+func (n *node) insert(pattern string, handlers []HandlerFunc) {
+	cur, path := n, pattern
+	var names []string
+	for path != "" {
+		i := strings.IndexAny(path, ":*")
+		if i < 0 {
+			cur = cur.insertStatic(path)
+			break
+		}
+		if i > 0 {
+			cur = cur.insertStatic(path[:i])
+			path = path[i:]
+		}
+
+		if path[0] == '*' {
+			if offset := len(pattern) - len(path); offset == 0 || pattern[offset-1] != '/' {
+				panic(fmt.Sprintf("gee: catch-all must follow a '/' in pattern %q", pattern))
+			}
+			if strings.ContainsAny(path[1:], "/:*") {
+				panic(fmt.Sprintf("gee: catch-all is only allowed at the end of pattern %q", pattern))
+			}
+			cur = cur.insertWild(catchAll, path, pattern)
+			break
+		}
+
+		end := 1
+		for end < len(path) && isParamByte(path[end]) {
+			end++
+		}
+		name := path[1:end]
+		if name == "" {
+			panic(fmt.Sprintf("gee: wildcard must be named in pattern %q", pattern))
+		}
+		if end < len(path) && (path[end] == ':' || path[end] == '*') {
+			panic(fmt.Sprintf("gee: wildcards must be separated by static text in pattern %q", pattern))
+		}
+		for _, existing := range names {
+			if existing == name {
+				panic(fmt.Sprintf("gee: duplicate wildcard %q in pattern %q", name, pattern))
+			}
+		}
+		names = append(names, name)
+		cur = cur.insertWild(param, path[:end], pattern)
+		path = path[end:]
+	}
+
+	if cur.pattern != "" {
+		panic(fmt.Sprintf("gee: pattern %q conflicts with existing route %q", pattern, cur.pattern))
+	}
+	cur.pattern = pattern
+	cur.handlers = handlers
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// insertStatic walks or creates the static children of n matching path,
+// splitting a child when path only shares a prefix with it
+func (n *node) insertStatic(path string) *node {
+	for {
+		i := strings.IndexByte(n.indices, path[0])
+		if i < 0 {
+			child := &node{path: path, typ: static}
+			n.indices += path[:1]
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			rest := *child
+			rest.path = child.path[l:]
+			*child = node{
+				path:     child.path[:l],
+				typ:      static,
+				indices:  rest.path[:1],
+				children: []*node{&rest},
+			}
+		}
+		if l == len(path) {
+			return child
+		}
+		n, path = child, path[l:]
 	}
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+// insertWild returns the param or catch-all child of n named by path
+func (n *node) insertWild(typ nodeType, path string, pattern string) *node {
+	child := &n.paramChild
+	if typ == catchAll {
+		child = &n.catchAllChild
+	}
+	if *child == nil {
+		*child = &node{path: path, typ: typ}
+	} else if (*child).path != path {
+		panic(fmt.Sprintf("gee: wildcard %q in pattern %q conflicts with existing wildcard %q", path, pattern, (*child).path))
 	}
-	child.insert(pattern, parts, height+1)
+	return *child
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
+// search matches path against n and its descendants, filling params on success
+func (n *node) search(path string, params *Params) *node {
+	switch n.typ {
+	case static:
+		if !strings.HasPrefix(path, n.path) {
 			return nil
 		}
+		return n.searchChildren(path[len(n.path):], params)
+	case catchAll:
+		if len(n.path) > 1 {
+			*params = append(*params, Param{Key: n.path[1:], Value: path})
+		}
 		return n
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
-
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
+	// a param value is never empty and never contains '/', when static text
+	// follows in the same segment the shortest value that matches wins
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	name, l := n.path[1:], len(*params)
+	for i := 1; i <= end; i++ {
+		if i < end && strings.IndexByte(n.indices, path[i]) < 0 {
+			continue
+		}
+		*params = append((*params)[:l], Param{Key: name, Value: path[:i]})
+		if result := n.searchChildren(path[i:], params); result != nil {
 			return result
 		}
 	}
+	*params = (*params)[:l]
+	return nil
+}
+
+func (n *node) searchChildren(path string, params *Params) *node {
+	if path == "" {
+		if n.pattern != "" {
+			return n
+		}
+		if n.catchAllChild != nil {
+			return n.catchAllChild.search(path, params)
+		}
+		return nil
+	}
 
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		if result := n.children[i].search(path, params); result != nil {
+			return result
+		}
+	}
+	if n.paramChild != nil {
+		if result := n.paramChild.search(path, params); result != nil {
+			return result
+		}
+	}
+	if n.catchAllChild != nil {
+		return n.catchAllChild.search(path, params)
+	}
 	return nil
 }
 
-func (n *node) travel(list *([]*node)) {
-	if n.pattern != "" {
-		*list = append(*list, n)
+// searchFold matches path like search, ignoring the case of the static
+// text, it returns path with the case of the registered pattern
+func (n *node) searchFold(path string) (string, bool) {
+	switch n.typ {
+	case static:
+		if len(path) < len(n.path) || !strings.EqualFold(path[:len(n.path)], n.path) {
+			return "", false
+		}
+		rest, ok := n.searchFoldChildren(path[len(n.path):])
+		return n.path + rest, ok
+	case catchAll:
+		return path, true
 	}
-	for _, child := range n.children {
-		child.travel(list)
+
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	for i := 1; i <= end; i++ {
+		if i < end && indexFold(n.indices, path[i]) < 0 {
+			continue
+		}
+		if rest, ok := n.searchFoldChildren(path[i:]); ok {
+			return path[:i] + rest, true
+		}
 	}
+	return "", false
 }
 
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
+func (n *node) searchFoldChildren(path string) (string, bool) {
+	if path == "" {
+		return "", n.pattern != "" || n.catchAllChild != nil
+	}
+	for i, child := range n.children {
+		if toLowerByte(n.indices[i]) != toLowerByte(path[0]) {
+			continue
+		}
+		if fixed, ok := child.searchFold(path); ok {
+			return fixed, true
 		}
 	}
-	return nil
+	if n.paramChild != nil {
+		if fixed, ok := n.paramChild.searchFold(path); ok {
+			return fixed, true
+		}
+	}
+	if n.catchAllChild != nil {
+		return n.catchAllChild.searchFold(path)
+	}
+	return "", false
 }
 
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+func toLowerByte(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+func indexFold(s string, b byte) int {
+	b = toLowerByte(b)
+	for i := 0; i < len(s); i++ {
+		if toLowerByte(s[i]) == b {
+			return i
 		}
 	}
-	return nodes
+	return -1
+}
+
+func (n *node) travel(list *([]*node)) {
+	if n.pattern != "" {
+		*list = append(*list, n)
+	}
+	for _, child := range n.children {
+		child.travel(list)
+	}
+	if n.paramChild != nil {
+		n.paramChild.travel(list)
+	}
+	if n.catchAllChild != nil {
+		n.catchAllChild.travel(list)
+	}
 }
@@ -0,0 +1,169 @@
+package gee
+
+import (
+	"strings"
+	"testing"
+)
+
+// parsePattern splits a pattern into the parts of a trieNode,
+// only one * is allowed
+func parsePattern(pattern string) []string {
+	vs := strings.Split(pattern, "/")
+
+	parts := make([]string, 0)
+	for _, item := range vs {
+		if item != "" {
+			parts = append(parts, item)
+			if item[0] == '*' {
+				break
+			}
+		}
+	}
+	return parts
+}
+
+// trieNode is the segment trie used by router before the radix tree,
+// it is only kept as the baseline of the benchmarks below
+type trieNode struct {
+	pattern  string
+	part     string
+	children []*trieNode
+	isWild   bool
+}
+
+func (n *trieNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+
+	part := parts[height]
+	child := n.matchChild(part)
+	if child == nil {
+		child = &trieNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *trieNode) search(parts []string, height int) *trieNode {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	children := n.matchChildren(part)
+
+	for _, child := range children {
+		result := child.search(parts, height+1)
+		if result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+func (n *trieNode) matchChild(part string) *trieNode {
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *trieNode) matchChildren(part string) []*trieNode {
+	nodes := make([]*trieNode, 0)
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// getRoute is the lookup of the old router, params included
+func (n *trieNode) getRoute(path string) (*trieNode, map[string]string) {
+	searchParts := parsePattern(path)
+	params := make(map[string]string)
+	result := n.search(searchParts, 0)
+	if result == nil {
+		return nil, nil
+	}
+	for index, part := range parsePattern(result.pattern) {
+		if part[0] == ':' {
+			params[part[1:]] = searchParts[index]
+		}
+		if part[0] == '*' && len(part) > 1 {
+			params[part[1:]] = strings.Join(searchParts[index:], "/")
+			break
+		}
+	}
+	return result, params
+}
+
+var benchPatterns = []string{
+	"/",
+	"/about",
+	"/contact",
+	"/users",
+	"/users/:id",
+	"/users/:id/posts",
+	"/users/:id/posts/:post",
+	"/users/:id/followers",
+	"/articles",
+	"/articles/new",
+	"/articles/:slug",
+	"/articles/:slug/comments",
+	"/api/v1/status",
+	"/api/v1/items",
+	"/api/v1/items/:item",
+	"/api/v2/items/:item",
+	"/assets/*filepath",
+}
+
+var benchPaths = []string{
+	"/about",
+	"/users/42/posts/7",
+	"/articles/hello-world/comments",
+	"/api/v2/items/12",
+	"/assets/css/site.css",
+}
+
+func BenchmarkRadixGetRoute(b *testing.B) {
+	root := &node{}
+	for _, pattern := range benchPatterns {
+		root.insert(pattern, nil)
+	}
+	params := make(Params, 0, 8)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			params = params[:0]
+			if root.search(path, &params) == nil {
+				b.Fatalf("%s should match", path)
+			}
+		}
+	}
+}
+
+func BenchmarkTrieGetRoute(b *testing.B) {
+	root := &trieNode{}
+	for _, pattern := range benchPatterns {
+		root.insert(pattern, parsePattern(pattern), 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchPaths {
+			if n, _ := root.getRoute(path); n == nil {
+				b.Fatalf("%s should match", path)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package gee
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRadixPriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/users/new", nil)
+	r.addRoute("GET", "/users/:id", nil)
+	r.addRoute("GET", "/users/:id/edit", nil)
+	r.addRoute("GET", "/users/*rest", nil)
+	r.addRoute("GET", "/file/:name.:ext", nil)
+	r.addRoute("GET", "/file/:name", nil)
+	r.addRoute("GET", "/v:version/status", nil)
+	r.addRoute("GET", "/static/*", nil)
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/users/new", "/users/new", map[string]string{}},
+		{"/users/newer", "/users/:id", map[string]string{"id": "newer"}},
+		{"/users/42", "/users/:id", map[string]string{"id": "42"}},
+		{"/users/42/edit", "/users/:id/edit", map[string]string{"id": "42"}},
+		{"/users/42/delete", "/users/*rest", map[string]string{"rest": "42/delete"}},
+		{"/users/", "/users/*rest", map[string]string{"rest": ""}},
+		{"/file/report.pdf", "/file/:name.:ext", map[string]string{"name": "report", "ext": "pdf"}},
+		{"/file/archive.tar.gz", "/file/:name.:ext", map[string]string{"name": "archive", "ext": "tar.gz"}},
+		{"/file/README", "/file/:name", map[string]string{"name": "README"}},
+		{"/v2/status", "/v:version/status", map[string]string{"version": "2"}},
+		{"/static/js/app.js", "/static/*", map[string]string{}},
+	}
+	for _, tc := range cases {
+		n, ps := r.getRoute("GET", tc.path)
+		if n == nil {
+			t.Fatalf("%s should match %s", tc.path, tc.pattern)
+		}
+		if n.pattern != tc.pattern || !reflect.DeepEqual(ps, tc.params) {
+			t.Fatalf("%s matched %s %v, want %s %v", tc.path, n.pattern, ps, tc.pattern, tc.params)
+		}
+	}
+
+	for _, path := range []string{"/users", "/file/", "/v/status", "/unknown"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't match, got %s", path, n.pattern)
+		}
+	}
+}
+
+func TestRadixSplit(t *testing.T) {
+	r := newRouter()
+	for _, pattern := range []string{"/search", "/support", "/s", "/src/:file", "/"} {
+		r.addRoute("GET", pattern, nil)
+	}
+	for _, path := range []string{"/search", "/support", "/s", "/", "/src/main.go"} {
+		if n, _ := r.getRoute("GET", path); n == nil {
+			t.Fatalf("%s should match", path)
+		}
+	}
+	for _, path := range []string{"/se", "/sup", "/src", "/searching"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't match, got %s", path, n.pattern)
+		}
+	}
+	if len(r.getRoutes("GET")) != 5 {
+		t.Fatal("the number of routes should be 5")
+	}
+}
+
+func TestRadixConflicts(t *testing.T) {
+	cases := []struct {
+		registered []string
+		pattern    string
+	}{
+		{[]string{"/hello/:name"}, "/hello/:id"},
+		{[]string{"/hello/:name"}, "/hello/:name"},
+		{[]string{"/assets/*filepath"}, "/assets/*file"},
+		{nil, "/assets/*filepath/more"},
+		{nil, "/assets*filepath"},
+		{nil, "/users/:"},
+		{nil, "/users/:id:name"},
+		{nil, "/users/:id/:id"},
+		{nil, "users"},
+	}
+	for _, tc := range cases {
+		r := newRouter()
+		for _, pattern := range tc.registered {
+			r.addRoute("GET", pattern, nil)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %s after %v should panic", tc.pattern, tc.registered)
+				}
+			}()
+			r.addRoute("GET", tc.pattern, nil)
+		}()
+	}
+}
@@ -0,0 +1,222 @@
+package gee
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a field failing one rule of its `binding` tag
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is returned by Validate and the binding methods,
+// it can be rendered directly with c.JSON
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks obj against the `binding` tags of its fields.
+// The tag is a comma separated list of rules:
+//
+//	required     the field must not be the zero value, nor empty
+//	omitempty    skip the other rules when the field is empty
+//	min=n, max=n bounds of a number, or of the length of a string, slice or map
+//	len=n        exact length of a string, slice or map
+//	email        a plain e-mail address
+//	oneof=a b c  one of the space separated values
+//
+// Only the first failing rule of a field is reported,
+// and a nil pointer field is only checked by required.
+// Nested structs, and slices of structs, are validated recursively.
+// It returns nil or ValidationErrors, and panics on unknown rules.
+func Validate(obj interface{}) error {
+	var errs ValidationErrors
+	validateValue(reflect.ValueOf(obj), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, prefix, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i), errs)
+		}
+	}
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && (!sf.Anonymous || sf.Type.Kind() != reflect.Struct) {
+			continue
+		}
+		name := prefix
+		if !sf.Anonymous {
+			name = fieldName(sf)
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+		}
+		field := v.Field(i)
+		if tag := sf.Tag.Get("binding"); tag != "" && tag != "-" {
+			validateField(field, name, tag, errs)
+		}
+		validateValue(field, name, errs)
+	}
+}
+
+// fieldName is the name a client uses for the field
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name := sf.Tag.Get(key)
+		if i := strings.IndexByte(name, ','); i >= 0 {
+			name = name[:i]
+		}
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+func validateField(v reflect.Value, name string, tag string, errs *ValidationErrors) {
+	rules := strings.Split(tag, ",")
+	empty := isEmpty(v)
+	for _, rule := range rules {
+		if rule == "omitempty" && empty {
+			return
+		}
+	}
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	isNil := v.Kind() == reflect.Ptr
+
+	for _, rule := range rules {
+		key, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			key, param = rule[:i], rule[i+1:]
+		}
+		if isNil && key != "required" {
+			continue // only required applies to a missing optional field
+		}
+		var message string
+		switch key {
+		case "omitempty":
+		case "required":
+			if empty {
+				message = fmt.Sprintf("%s is required", name)
+			}
+		case "min", "max", "len":
+			message = checkBound(v, name, key, param)
+		case "email":
+			if v.Kind() != reflect.String {
+				panic(fmt.Sprintf("gee: rule email used on %s of type %s", name, v.Type()))
+			}
+			if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+				message = fmt.Sprintf("%s must be a valid email address", name)
+			}
+		case "oneof":
+			value := fmt.Sprint(v.Interface())
+			found := false
+			for _, option := range strings.Fields(param) {
+				if option == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				message = fmt.Sprintf("%s must be one of [%s]", name, param)
+			}
+		default:
+			panic(fmt.Sprintf("gee: unknown validation rule %q on %s", key, name))
+		}
+		if message != "" {
+			*errs = append(*errs, FieldError{Field: name, Tag: key, Param: param, Message: message})
+			return
+		}
+	}
+}
+
+// checkBound applies min, max or len to v, it returns the error message or ""
+func checkBound(v reflect.Value, name string, key string, param string) string {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("gee: invalid parameter %q for rule %s on %s", param, key, name))
+	}
+
+	var value float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		value, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		value, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	default:
+		panic(fmt.Sprintf("gee: rule %s used on %s of type %s", key, name, v.Type()))
+	}
+
+	switch {
+	case key == "min" && value < bound:
+		if unit == "" {
+			return fmt.Sprintf("%s must be at least %s", name, param)
+		}
+		return fmt.Sprintf("%s must have at least %s%s", name, param, unit)
+	case key == "max" && value > bound:
+		if unit == "" {
+			return fmt.Sprintf("%s must be at most %s", name, param)
+		}
+		return fmt.Sprintf("%s must have at most %s%s", name, param, unit)
+	case key == "len" && value != bound:
+		return fmt.Sprintf("%s must have exactly %s%s", name, param, unit)
+	}
+	return ""
+}
@@ -0,0 +1,578 @@
+package gee
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// message types of WebSocket frames, see RFC 6455 section 11.8
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// close codes, see RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	wsGUID                = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultWSReadLimit    = 1 << 20
+	maxControlPayload     = 125
+	closeHandshakeTimeout = time.Second
+)
+
+var (
+	// ErrWSClosed is returned when writing after the close frame was sent
+	ErrWSClosed = errors.New("gee: websocket closed")
+	// ErrWSReadLimit is returned when a message is larger than the read limit
+	ErrWSReadLimit = errors.New("gee: websocket message exceeds the read limit")
+)
+
+// WSCloseError is returned by ReadMessage when the peer closed the connection,
+// or when the connection was failed with Code
+type WSCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *WSCloseError) Error() string {
+	return fmt.Sprintf("gee: websocket closed with code %d %s", e.Code, e.Text)
+}
+
+// WSConfig configures the WebSocket handshake and connection
+type WSConfig struct {
+	// ReadLimit is the largest message read, 1MB by default
+	ReadLimit int64
+	// Subprotocols supported by the server, in order of preference
+	Subprotocols []string
+	// CheckOrigin accepts the request, by default the Origin header
+	// must be absent or have the same host as the request
+	CheckOrigin func(r *http.Request) bool
+}
+
+// WSHandler handles an upgraded WebSocket connection, the connection
+// is closed when it returns
+type WSHandler func(c *Context, ws *WSConn)
+
+// WS registers a WebSocket endpoint for GET requests on pattern, the
+// middlewares of the group run before the upgrade
+func (group *RouterGroup) WS(pattern string, handler WSHandler, config ...WSConfig) *Route {
+	return group.GET(pattern, func(c *Context) {
+		ws, err := c.Upgrade(config...)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		handler(c, ws)
+	})
+}
+
+// Upgrade completes the WebSocket handshake of RFC 6455 and takes over the
+// connection. On failure the error response is already written.
+func (c *Context) Upgrade(config ...WSConfig) (*WSConn, error) {
+	var cfg WSConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = defaultWSReadLimit
+	}
+	if cfg.CheckOrigin == nil {
+		cfg.CheckOrigin = sameOrigin
+	}
+
+	fail := func(code int, message string) (*WSConn, error) {
+		c.Fail(code, message)
+		return nil, errors.New("gee: websocket handshake: " + message)
+	}
+	req := c.Req
+	if req.Method != "GET" {
+		return fail(http.StatusMethodNotAllowed, "method must be GET")
+	}
+	if !headerHasToken(req.Header, "Connection", "upgrade") || !headerHasToken(req.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket upgrade request")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !cfg.CheckOrigin(req) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+	subprotocol := selectSubprotocol(req, cfg.Subprotocols)
+	engine := c.engine
+	engine.mu.Lock()
+	shuttingDown := engine.shuttingDown
+	engine.mu.Unlock()
+	if shuttingDown {
+		return fail(http.StatusServiceUnavailable, "server shutting down")
+	}
+
+	c.Status(http.StatusSwitchingProtocols)
+	conn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+	var handshake strings.Builder
+	handshake.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	handshake.WriteString("Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n")
+	if subprotocol != "" {
+		handshake.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	handshake.WriteString("\r\n")
+	// the server may have set a deadline for the HTTP response
+	conn.SetDeadline(time.Time{})
+	if _, err := conn.Write([]byte(handshake.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws := newWSConn(conn, brw.Reader, cfg.ReadLimit, subprotocol)
+	ws.engine = engine
+	engine.mu.Lock()
+	if engine.websockets == nil {
+		engine.websockets = make(map[*WSConn]struct{})
+	}
+	engine.websockets[ws] = struct{}{}
+	engine.mu.Unlock()
+	return ws, nil
+}
+
+// wsAccept computes the Sec-WebSocket-Accept of key
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			protocol = strings.TrimSpace(protocol)
+			for _, s := range supported {
+				if s == protocol {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// WSConn is a server side WebSocket connection. One goroutine may read
+// while others write, messages written concurrently are not interleaved.
+type WSConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	readLimit   int64
+	subprotocol string
+	readErr     error
+	engine      *Engine
+
+	mu        sync.Mutex // guards the writes and closeSent
+	dataMu    sync.Mutex // held while a data message is written
+	closeSent bool
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newWSConn(conn net.Conn, br *bufio.Reader, readLimit int64, subprotocol string) *WSConn {
+	ws := &WSConn{conn: conn, br: br, readLimit: readLimit, subprotocol: subprotocol}
+	ws.pingHandler = func(data []byte) error {
+		err := ws.WriteControl(PongMessage, data)
+		if err == ErrWSClosed {
+			return nil
+		}
+		return err
+	}
+	ws.pongHandler = func([]byte) error { return nil }
+	return ws
+}
+
+// Subprotocol returns the subprotocol selected during the handshake
+func (ws *WSConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// SetReadLimit changes the largest message read
+func (ws *WSConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// SetPingHandler sets the handler of the pings received while reading,
+// by default it answers with a pong
+func (ws *WSConn) SetPingHandler(h func(data []byte) error) {
+	ws.pingHandler = h
+}
+
+// SetPongHandler sets the handler of the pongs received while reading
+func (ws *WSConn) SetPongHandler(h func(data []byte) error) {
+	ws.pongHandler = h
+}
+
+func (ws *WSConn) SetReadDeadline(t time.Time) error  { return ws.conn.SetReadDeadline(t) }
+func (ws *WSConn) SetWriteDeadline(t time.Time) error { return ws.conn.SetWriteDeadline(t) }
+func (ws *WSConn) RemoteAddr() net.Addr               { return ws.conn.RemoteAddr() }
+
+// ReadMessage reads the next text or binary message, reassembling the
+// fragments and handling the control frames received meanwhile. It returns
+// a *WSCloseError once the peer closed the connection, or once the
+// connection failed because the peer broke the protocol.
+func (ws *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	if ws.readErr != nil {
+		return 0, nil, ws.readErr
+	}
+	messageType, data, err = ws.readMessage()
+	if err != nil {
+		ws.readErr = err
+	}
+	return messageType, data, err
+}
+
+func (ws *WSConn) readMessage() (int, []byte, error) {
+	var messageType int
+	var message []byte
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(ws.br, header[:]); err != nil {
+			return 0, nil, err
+		}
+		fin := header[0]&0x80 != 0
+		opcode := int(header[0] & 0x0f)
+		if header[0]&0x70 != 0 {
+			return 0, nil, ws.fail(CloseProtocolError, "reserved bits set")
+		}
+		if header[1]&0x80 == 0 {
+			return 0, nil, ws.fail(CloseProtocolError, "client frames must be masked")
+		}
+		length := int64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			if ext[0]&0x80 != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "invalid payload length")
+			}
+			length = int64(binary.BigEndian.Uint64(ext[:]))
+		}
+
+		if opcode >= CloseMessage {
+			if !fin || length > maxControlPayload {
+				return 0, nil, ws.fail(CloseProtocolError, "invalid control frame")
+			}
+		} else {
+			switch {
+			case opcode == continuationFrame && messageType == 0:
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			case (opcode == TextMessage || opcode == BinaryMessage) && messageType != 0:
+				return 0, nil, ws.fail(CloseProtocolError, "expected a continuation frame")
+			case opcode != continuationFrame && opcode != TextMessage && opcode != BinaryMessage:
+				return 0, nil, ws.fail(CloseProtocolError, "unknown opcode")
+			}
+			if int64(len(message))+length > ws.readLimit {
+				ws.fail(CloseMessageTooBig, "message too big")
+				return 0, nil, ErrWSReadLimit
+			}
+		}
+
+		payload, err := ws.readPayload(length)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := ws.pingHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := ws.pongHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			messageType = opcode
+		}
+		message = append(message, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, ws.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+func (ws *WSConn) readPayload(length int64) ([]byte, error) {
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		return nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return payload, nil
+}
+
+// handleClose answers the close frame of the peer with the same code
+func (ws *WSConn) handleClose(payload []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code, text = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+		if !validCloseCode(code) {
+			return ws.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return ws.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in close reason")
+		}
+	}
+	reply := []byte{}
+	if code != CloseNoStatusReceived {
+		reply = payload[:2]
+	}
+	ws.writeClose(reply)
+	ws.untrack()
+	ws.conn.Close()
+	return &WSCloseError{Code: code, Text: text}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1011:
+		return false
+	}
+	return code != 1004 && code != CloseNoStatusReceived && code != CloseAbnormalClosure
+}
+
+// fail closes the connection with code after a protocol violation of the peer
+func (ws *WSConn) fail(code int, text string) error {
+	ws.writeClose(closePayload(code, text))
+	ws.untrack()
+	ws.conn.Close()
+	return &WSCloseError{Code: code, Text: text}
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+// writeClose sends the close frame once
+func (ws *WSConn) writeClose(payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent {
+		return ErrWSClosed
+	}
+	ws.closeSent = true
+	ws.conn.SetWriteDeadline(time.Now().Add(closeHandshakeTimeout))
+	return ws.writeFrameLocked(CloseMessage, true, payload)
+}
+
+// WriteMessage sends data as one text or binary message
+func (ws *WSConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("gee: invalid websocket message type %d", messageType)
+	}
+	ws.dataMu.Lock()
+	defer ws.dataMu.Unlock()
+	return ws.writeFrame(messageType, true, data)
+}
+
+// WriteJSON sends v encoded as JSON in a text message
+func (ws *WSConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v
+func (ws *WSConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// NextWriter returns a writer sending a message in fragments, one frame
+// per Write, the message ends when the writer is closed. Other data
+// messages wait until then, control frames may be sent in between.
+func (ws *WSConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("gee: invalid websocket message type %d", messageType)
+	}
+	ws.dataMu.Lock()
+	return &wsWriter{ws: ws, opcode: messageType}, nil
+}
+
+// WriteControl sends a ping or pong frame
+func (ws *WSConn) WriteControl(messageType int, data []byte) error {
+	if messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("gee: invalid websocket control type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("gee: websocket control payload too long")
+	}
+	return ws.writeFrame(messageType, true, data)
+}
+
+// Ping sends a ping, the pong is given to the pong handler while reading
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.WriteControl(PingMessage, data)
+}
+
+// Close sends a normal closure and closes the connection
+func (ws *WSConn) Close() error {
+	return ws.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode sends a close frame with code and text, e.g.
+// CloseGoingAway on shutdown, and closes the connection
+func (ws *WSConn) CloseWithCode(code int, text string) error {
+	ws.writeClose(closePayload(code, text))
+	ws.untrack()
+	return ws.conn.Close()
+}
+
+// untrack forgets the connection once closed, Shutdown has nothing to do
+func (ws *WSConn) untrack() {
+	if ws.engine == nil {
+		return
+	}
+	ws.engine.mu.Lock()
+	delete(ws.engine.websockets, ws)
+	ws.engine.mu.Unlock()
+}
+
+func (ws *WSConn) writeFrame(opcode int, fin bool, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent {
+		return ErrWSClosed
+	}
+	return ws.writeFrameLocked(opcode, fin, payload)
+}
+
+// writeFrameLocked writes an unmasked frame, as the server must
+func (ws *WSConn) writeFrameLocked(opcode int, fin bool, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame = append(frame, b0)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+type wsWriter struct {
+	ws      *WSConn
+	opcode  int
+	started bool
+	closed  bool
+}
+
+func (w *wsWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWSClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.ws.writeFrame(w.frameOpcode(), false, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *wsWriter) frameOpcode() int {
+	if w.started {
+		return continuationFrame
+	}
+	w.started = true
+	return w.opcode
+}
+
+func (w *wsWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.ws.dataMu.Unlock()
+	return w.ws.writeFrame(w.frameOpcode(), true, nil)
+}
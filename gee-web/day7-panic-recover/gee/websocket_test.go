@@ -0,0 +1,234 @@
+package gee
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is a minimal client sending masked frames
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string, headers ...string) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{t: t, conn: conn, br: br}, res
+}
+
+func (c *wsClient) writeFrame(b0 byte, payload []byte, masked bool) {
+	frame := []byte{b0}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	default:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) readFrame() (opcode int, payload []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if header[1]&0x80 != 0 {
+		c.t.Fatal("server frames must not be masked")
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+func (c *wsClient) expectClose(code int) {
+	opcode, payload := c.readFrame()
+	if opcode != CloseMessage || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Fatalf("expected close %d, got opcode %d %q", code, opcode, payload)
+	}
+}
+
+func newWSEngine() *Engine {
+	r := New()
+	r.Use(func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.Fail(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		c.Next()
+	})
+	r.WS("/echo", func(c *Context, ws *WSConn) {
+		for {
+			typ, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "fragments" {
+				w, _ := ws.NextWriter(TextMessage)
+				w.Write([]byte("frag"))
+				w.Write([]byte("ments"))
+				w.Close()
+				continue
+			}
+			ws.WriteMessage(typ, data)
+		}
+	}, WSConfig{ReadLimit: 1024, Subprotocols: []string{"chat"}})
+	return r
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv := httptest.NewServer(newWSEngine())
+	defer srv.Close()
+
+	c, res := dialWS(t, srv, "/echo?token=secret", "Sec-WebSocket-Protocol", "superchat, chat")
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		res.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("unexpected handshake %d %v", res.StatusCode, res.Header)
+	}
+
+	c.writeFrame(0x81, []byte("hello"), true)
+	if opcode, payload := c.readFrame(); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("unexpected echo %d %q", opcode, payload)
+	}
+
+	// a fragmented message with a ping in between
+	c.writeFrame(0x02, []byte("bin"), true)
+	c.writeFrame(0x89, []byte("are you there"), true)
+	c.writeFrame(0x80, []byte(strings.Repeat("a", 200)), true)
+	if opcode, payload := c.readFrame(); opcode != PongMessage || string(payload) != "are you there" {
+		t.Fatalf("expected a pong, got %d %q", opcode, payload)
+	}
+	if opcode, payload := c.readFrame(); opcode != BinaryMessage || string(payload) != "bin"+strings.Repeat("a", 200) {
+		t.Fatalf("unexpected reassembled message %d %q", opcode, payload)
+	}
+
+	c.writeFrame(0x81, []byte("fragments"), true)
+	var got []byte
+	for _, want := range []int{TextMessage, continuationFrame, continuationFrame} {
+		opcode, payload := c.readFrame()
+		if opcode != want {
+			t.Fatalf("expected opcode %d, got %d", want, opcode)
+		}
+		got = append(got, payload...)
+	}
+	if string(got) != "fragments" {
+		t.Fatalf("unexpected fragments %q", got)
+	}
+
+	c.writeFrame(0x88, closePayload(CloseGoingAway, "bye"), true)
+	c.expectClose(CloseGoingAway)
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	srv := httptest.NewServer(newWSEngine())
+	defer srv.Close()
+
+	c, _ := dialWS(t, srv, "/echo?token=secret")
+	c.writeFrame(0x81, []byte("unmasked"), false)
+	c.expectClose(CloseProtocolError)
+
+	c, _ = dialWS(t, srv, "/echo?token=secret")
+	c.writeFrame(0x81, []byte(strings.Repeat("a", 2000)), true)
+	c.expectClose(CloseMessageTooBig)
+
+	c, _ = dialWS(t, srv, "/echo?token=secret")
+	c.writeFrame(0x81, []byte{0xff, 0xfe}, true)
+	c.expectClose(CloseInvalidFramePayloadData)
+
+	c, _ = dialWS(t, srv, "/echo?token=secret")
+	c.writeFrame(0x80, []byte("orphan"), true)
+	c.expectClose(CloseProtocolError)
+}
+
+func TestWebSocketHandshakeErrors(t *testing.T) {
+	srv := httptest.NewServer(newWSEngine())
+	defer srv.Close()
+
+	if _, res := dialWS(t, srv, "/echo"); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("middlewares should run before the upgrade, got %d", res.StatusCode)
+	}
+	if _, res := dialWS(t, srv, "/echo?token=secret", "Sec-WebSocket-Version", "8"); res.StatusCode != http.StatusUpgradeRequired ||
+		res.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Fatalf("expected 426, got %d %v", res.StatusCode, res.Header)
+	}
+	if _, res := dialWS(t, srv, "/echo?token=secret", "Origin", "https://evil.example.com"); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin request should be 403, got %d", res.StatusCode)
+	}
+	res, err := http.Get(srv.URL + "/echo?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain request should be 400, got %d", res.StatusCode)
+	}
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	r := newWSEngine()
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	c, _ := dialWS(t, srv, "/echo?token=secret")
+	c.writeFrame(0x81, []byte("ready"), true)
+	c.readFrame()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown should not wait for the websocket, got %v", err)
+	}
+	c.expectClose(CloseGoingAway)
+}